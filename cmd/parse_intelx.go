@@ -6,12 +6,10 @@ import (
     "path/filepath"
     //"fmt"
     "os"
-    "time"
-    "strings"
 
     "github.com/helviojunior/intelparser/internal/ascii"
     "github.com/helviojunior/intelparser/internal/tools"
-    "github.com/helviojunior/intelparser/internal/disk"
+    "github.com/helviojunior/intelparser/pkg/archive"
     "github.com/helviojunior/intelparser/pkg/log"
     "github.com/helviojunior/intelparser/pkg/runner"
     //"github.com/helviojunior/intelparser/pkg/database"
@@ -23,39 +21,52 @@ import (
     "github.com/spf13/cobra"
 )
 
-func AddZipFile(temp_folder string, file_path string, virtual_path string) error {
-    var mime string
-    var dst string
-    var err error
+// AddArchiveFile sends the members of the archive (zip, tar, gz, bz2 or xz,
+// including nested archives) to the runner, streamed without extraction
+func AddArchiveFile(file_path string, virtual_path string) error {
     file_name := filepath.Base(file_path)
     logger := log.With("file", file_name)
 
     logger.Debug("Checking file")
-    if mime, err = tools.GetMimeType(file_path); err != nil {
-        logger.Debug("Error getting mime type", "err", err)
-        return err
+    if !archive.IsArchive(file_path) {
+        return archive.ErrNotArchive
     }
 
-    logger.Debug("Mime type", "mime", mime)
-    if mime != "application/zip" {
-        return errors.New("invalid file type")
-    }
+    log.Info("Parsing archive file", "file", file_name)
+
+    info := false
+    ao := archiveOptions()
+    ao.FirstNames = []string{"Info.csv"}
+    err := archive.Walk(file_path, virtual_path, ao, func(e archive.Entry) error {
+        item := runner.ArchiveFileItem(e)
+        switch item.Name() {
+        case "Info.csv":
+            info = true
+            if err := scanRunner.ParsePositionalFile(item); err != nil {
+                logger.Error("error parsing Info.csv", "path", item.VirtualPath, "err", err)
+            }
+            return nil
+        case "info.sqlite3":
+            item.Done()
+            return nil
+        }
 
-    if dst, err = tools.CreateDirFromFilename(temp_folder, file_path); err != nil {
-        logger.Debug("Error creating temp folder to extract zip file", "err", err)
+        if !addMailboxItem(item) {
+            scanRunner.Files <- item
+        }
+        return nil
+    })
+    if err != nil {
         return err
     }
-    defer tools.RemoveFolder(dst)
 
-    if err = tools.Unzip(file_path, dst); err != nil {
-        logger.Debug("Error extracting zip file", "temp_folder", dst, "err", err)
-        return err
+    if !info {
+        logger.Warn("File 'Info.csv' not found in archive")
     }
-
-    return AddFolder(temp_folder, dst, file_path, filepath.Join(virtual_path, file_name));
+    return nil
 }
 
-func AddFolder(temp_folder string, folder_path string, zip_source string, virtual_path string) error {
+func AddFolder(folder_path string, virtual_path string) error {
     //scanRunner.Files <- intelxCmdOptions.Path
 
     entries, err := os.ReadDir(folder_path)
@@ -69,12 +80,7 @@ func AddFolder(temp_folder string, folder_path string, zip_source string, virtua
         return errors.New("File 'Info.csv' not found") 
     }
 
-    if zip_source == "" {
-        log.Info("Parsing files in folder", "folder", folder_path)
-    }else {
-        file_name := filepath.Base(zip_source)
-        log.Info("Parsing ZIP file", "file", file_name)
-    }
+    log.Info("Parsing files in folder", "folder", folder_path)
 
     if err := scanRunner.ParsePositionalFile(runner.FileItem{
         RealPath: info,
@@ -85,14 +91,24 @@ func AddFolder(temp_folder string, folder_path string, zip_source string, virtua
 
     for _, e := range entries {
         if e.Name() != "Info.csv" && e.Name() != "info.sqlite3" {
-            scanRunner.Files <- runner.FileItem{
-                RealPath: filepath.Join(folder_path, e.Name()),
+            fp := filepath.Join(folder_path, e.Name())
+            if !e.IsDir() && archive.IsArchive(fp) {
+                if err := AddArchiveFile(fp, virtual_path); err != nil {
+                    log.Error("error parsing archive file", "file", e.Name(), "err", err)
+                }
+                continue
+            }
+
+            item := runner.FileItem{
+                RealPath: fp,
                 VirtualPath: filepath.Join(virtual_path, e.Name()),
             }
+            if !addMailboxItem(item) {
+                scanRunner.Files <- item
+            }
         }
     }
 
-    time.Sleep(time.Duration(time.Second * 10))
     return nil
 }
 
@@ -106,6 +122,9 @@ var intelxCmd = &cobra.Command{
 
 Parse IntelX downloaded files (ZIP or folder).
 
+Archives (zip, tar, tar.gz, tar.bz2, tar.xz, gz) are streamed without being
+extracted, including the nested ones (see --archive-depth).
+
 `)),
     Example: `
    - intelparser parse intelx -p "~/Desktop/Search 2025-02-05 10_48_28.zip"
@@ -116,7 +135,7 @@ Parse IntelX downloaded files (ZIP or folder).
         var err error
 
         if intelxCmdOptions.Path == "" && len(intelxCmdOptions.Path) == 0 {
-            return errors.New("a ZIP file or path must be specified")
+            return errors.New("an archive file or path must be specified")
         }
 
         if intelxCmdOptions.Path != "" && !tools.FileExists(intelxCmdOptions.Path) {
@@ -156,19 +175,13 @@ Parse IntelX downloaded files (ZIP or folder).
 
         log.Debug("starting parsing scanning", "path", intelxCmdOptions.Path, "type", ft)
 
-        di, err := disk.GetInfo(tempFolder, false)
-        if err != nil {
-            log.Error("Error getting disk stats", "path", tempFolder, "err", err)
-            os.Exit(2)
-        }
-
         go func() {
             defer close(scanRunner.Files)
 
             if ft == "file" {
                 //File
-                if err = AddZipFile(tempFolder, intelxCmdOptions.Path, ""); err != nil {
-                    log.Error("error parsing ZIP file", "err", err)
+                if err = AddArchiveFile(intelxCmdOptions.Path, ""); err != nil {
+                    log.Error("error parsing archive file", "err", err)
                 }
 
             }else{
@@ -177,7 +190,7 @@ Parse IntelX downloaded files (ZIP or folder).
                 info := filepath.Join(intelxCmdOptions.Path, "Info.csv")
                 if tools.FileExists(info) {
                     
-                    if err = AddFolder(tempFolder, intelxCmdOptions.Path, "", ""); err != nil {
+                    if err = AddFolder(intelxCmdOptions.Path, ""); err != nil {
                         log.Error("error", "err", err)
                     }
 
@@ -190,25 +203,15 @@ Parse IntelX downloaded files (ZIP or folder).
                     }
 
                     for _, e := range entries {
-                        if strings.ToLower(filepath.Ext(e.Name())) != ".zip" {
-                            log.Debug("Ignoring non ZIP file", "file", e.Name())
+                        fp := filepath.Join(intelxCmdOptions.Path, e.Name())
+                        if e.IsDir() || !archive.IsArchive(fp) {
+                            log.Debug("Ignoring non archive file", "file", e.Name())
                             continue
                         }
 
-                        fst, err := os.Stat(filepath.Join(intelxCmdOptions.Path, e.Name()))
-                        if err != nil {
-                            log.Error("Error getting file stats", "file", e.Name(), "err", err)
-                            os.Exit(2)
-                        }
-
-                        if di.Free <= uint64(5 * fst.Size()) {
-                            log.Error("No space left on temp path", "temp_path", tempFolder)
-                            os.Exit(2)
-                        }
-
-                        err = AddZipFile(tempFolder, filepath.Join(intelxCmdOptions.Path, e.Name()), "")
+                        err = AddArchiveFile(fp, "")
                         if err != nil {
-                            log.Debug("Error checking ZIP file", "file", e.Name(), "err", err)
+                            log.Debug("Error checking archive file", "file", e.Name(), "err", err)
                         }
                     }
                 }
@@ -221,27 +224,7 @@ Parse IntelX downloaded files (ZIP or folder).
         status := scanRunner.Run()
         scanRunner.Close()
 
-        diff := time.Now().Sub(startTime)
-        out := time.Time{}.Add(diff)
-
-        st := "Execution statistics\n"
-        st += "     -> Elapsed time.....: %s\n"
-        st += "     -> Files parsed.....: %s\n"
-        st += "     -> Skipped..........: %s\n"
-        st += "     -> Execution error..: %s\n"
-        st += "     -> Credentials......: %s\n"
-        st += "     -> URLs.............: %s\n"
-        st += "     -> E-mails..........: %s\n"
-
-        log.Warnf(st, 
-            out.Format("15:04:05"),
-            tools.FormatIntComma(status.Parsed), 
-            tools.FormatIntComma(status.Skipped),
-            tools.FormatIntComma(status.Error),
-            tools.FormatIntComma(status.Credential),
-            tools.FormatIntComma(status.Url),
-            tools.FormatIntComma(status.Email),
-        )
+        printParserStatistics(status)
 
         tools.RemoveFolder(tempFolder)
 
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+    "errors"
+    "io/fs"
+    "log/slog"
+    "path/filepath"
+    "os"
+
+    "github.com/helviojunior/intelparser/internal/ascii"
+    "github.com/helviojunior/intelparser/internal/tools"
+    "github.com/helviojunior/intelparser/pkg/archive"
+    "github.com/helviojunior/intelparser/pkg/log"
+    "github.com/helviojunior/intelparser/pkg/runner"
+    "github.com/helviojunior/intelparser/pkg/readers"
+    parsers "github.com/helviojunior/intelparser/pkg/runner/parsers"
+    resolver "github.com/helviojunior/gopathresolver"
+    "github.com/spf13/cobra"
+)
+
+var stealerParser *parsers.StealerParser
+
+// AddStealerArchiveFile extracts the archive (zip, tar, gz, bz2 or xz,
+// including nested archives) and parses it as a stealer log folder
+func AddStealerArchiveFile(temp_folder string, file_path string) error {
+    var dst string
+    var err error
+    file_name := filepath.Base(file_path)
+    logger := log.With("file", file_name)
+
+    logger.Debug("Checking file")
+    if !archive.IsArchive(file_path) {
+        return archive.ErrNotArchive
+    }
+
+    if dst, err = tools.CreateDirFromFilename(temp_folder, file_path); err != nil {
+        logger.Debug("Error creating temp folder to extract archive file", "err", err)
+        return err
+    }
+
+    // The extracted files are removed with the temp folder at the end of
+    // the execution, as the workers may still be reading them
+    if err = archive.Extract(file_path, dst, archiveOptions()); err != nil {
+        logger.Debug("Error extracting archive file", "temp_folder", dst, "err", err)
+        return err
+    }
+
+    log.Info("Parsing archive file", "file", file_name)
+    return AddStealerFolder(dst, file_name)
+}
+
+// AddStealerFolder looks for infected machine folders under folder_path and
+// sends all of their files to the runner
+func AddStealerFolder(folder_path string, virtual_path string) error {
+    victims := 0
+
+    err := filepath.WalkDir(folder_path, func(path string, d fs.DirEntry, err error) error {
+        if err != nil {
+            log.Debug("Error walking folder", "path", path, "err", err)
+            return nil
+        }
+
+        if !d.IsDir() || !parsers.IsStealerFolder(path) {
+            return nil
+        }
+
+        rel, _ := filepath.Rel(folder_path, path)
+        log.Debug("Infected machine folder found", "path", path)
+        if err := stealerParser.AddVictim(path); err != nil {
+            log.Error("Error reading infected machine folder", "path", path, "err", err)
+            return filepath.SkipDir
+        }
+        victims++
+
+        _ = filepath.WalkDir(path, func(fp string, fd fs.DirEntry, err error) error {
+            if err != nil || fd.IsDir() {
+                return nil
+            }
+
+            frel, _ := filepath.Rel(path, fp)
+            scanRunner.Files <- runner.FileItem{
+                RealPath: fp,
+                VirtualPath: filepath.Join(virtual_path, rel, frel),
+            }
+            return nil
+        })
+
+        // Nested folders belong to this infected machine
+        return filepath.SkipDir
+    })
+    if err != nil {
+        return err
+    }
+
+    if victims == 0 {
+        return errors.New("no stealer log folder found (Passwords.txt or UserInformation.txt)")
+    }
+
+    log.Info("Infected machines found", "count", victims)
+    return nil
+}
+
+var stealerCmdOptions = &readers.FileReaderOptions{}
+var stealerCmd = &cobra.Command{
+    Use:   "stealer",
+    Short: "Parse Stealer logs (RedLine, Raccoon, Lumma and alike)",
+    Long: ascii.LogoHelp(ascii.Markdown(`
+# parse stealer
+
+Parse Stealer logs (archive or folder).
+
+Each infected machine folder (the one with a Passwords.txt, All Passwords.txt,
+UserInformation.txt or System Info.txt file) is parsed as a group: its saved
+passwords, cookies, autofills and the machine information.
+`)),
+    Example: `
+   - intelparser parse stealer -p "~/Desktop/logs.zip"
+   - intelparser parse stealer -p "~/Desktop/logs/"
+   - intelparser parse stealer -p ~/Desktop/logs/ --write-elastic --write-elasticsearch-uri "http://127.0.0.1:9200/intelparser"
+`,
+    PreRunE: func(cmd *cobra.Command, args []string) error {
+        var err error
+
+        if stealerCmdOptions.Path == "" {
+            return errors.New("a ZIP file or path must be specified")
+        }
+
+        if !tools.FileExists(stealerCmdOptions.Path) {
+            return errors.New("ZIP file or path is not readable")
+        }
+
+        stealerCmdOptions.Path, err = resolver.ResolveFullPath(stealerCmdOptions.Path)
+        if err != nil {
+            return err
+        }
+
+        // An slog-capable logger to use with drivers and runners
+        logger := slog.New(log.Logger)
+
+        // Configure the driver
+        stealerParser, err = parsers.NewStealer(logger, *opts)
+        if err != nil {
+            return err
+        }
+        parserDriver = stealerParser
+
+        // Get the runner up. Basically, all of the subcommands will use this.
+        scanRunner, err = runner.NewRunner(logger, parserDriver, *opts, scanWriters)
+        if err != nil {
+            return err
+        }
+
+        return nil
+    },
+    Run: func(cmd *cobra.Command, args []string) {
+        var ft string
+        var err error
+
+        if ft, err = tools.FileType(stealerCmdOptions.Path); err != nil {
+            log.Error("error getting path type", "err", err)
+            os.Exit(2)
+        }
+
+        log.Debug("starting parsing scanning", "path", stealerCmdOptions.Path, "type", ft)
+
+        go func() {
+            defer close(scanRunner.Files)
+
+            if ft == "file" {
+                if err = AddStealerArchiveFile(tempFolder, stealerCmdOptions.Path); err != nil {
+                    log.Error("error parsing archive file", "err", err)
+                }
+            }else{
+                log.Info("Parsing files in folder", "folder", stealerCmdOptions.Path)
+                if err = AddStealerFolder(stealerCmdOptions.Path, ""); err != nil {
+                    log.Error("error", "err", err)
+                }
+            }
+        }()
+
+        log.Info("Starting Stealer parser")
+        status := scanRunner.Run()
+        scanRunner.Close()
+
+        printParserStatistics(status)
+
+        tools.RemoveFolder(tempFolder)
+    },
+}
+
+func init() {
+    parserCmd.AddCommand(stealerCmd)
+
+    stealerCmd.Flags().StringVarP(&stealerCmdOptions.Path, "path", "p", "", "A Path with Stealer log file(s).")
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+    "errors"
+    "io"
+    "io/fs"
+    "log/slog"
+    "path/filepath"
+    "os"
+    "strconv"
+    "strings"
+
+    "github.com/helviojunior/intelparser/internal/ascii"
+    "github.com/helviojunior/intelparser/internal/tools"
+    "github.com/helviojunior/intelparser/pkg/log"
+    "github.com/helviojunior/intelparser/pkg/runner"
+    parsers "github.com/helviojunior/intelparser/pkg/runner/parsers"
+    resolver "github.com/helviojunior/gopathresolver"
+    "github.com/spf13/cobra"
+)
+
+var telegramParser *parsers.TelegramParser
+var telegramCmdOptions = struct {
+    Path string
+}{}
+
+// AddTelegramExport sends the messages and the attached documents of the
+// export JSON (result.json) to the runner
+func AddTelegramExport(file_path string) error {
+    chats, err := parsers.ReadTelegramExport(file_path)
+    if err != nil {
+        return err
+    }
+
+    export_path := filepath.Dir(file_path)
+    for _, chat := range chats {
+        chat_name := strings.NewReplacer("/", "_", "\\", "_").Replace(chat.ChatName())
+        log.Info("Parsing Telegram chat", "chat", chat.ChatName(), "messages", len(chat.Messages))
+
+        for _, msg := range chat.Messages {
+            if msg.Type != "" && msg.Type != "message" {
+                continue
+            }
+
+            vpath := chat_name + "/" + strconv.FormatInt(msg.Id, 10)
+            telegramParser.AddMessage(vpath, chat, msg)
+
+            if text := msg.Content(); strings.TrimSpace(text) != "" {
+                scanRunner.Files <- runner.FileItem{
+                    VirtualPath: vpath + "/" + parsers.TelegramMessageFile,
+                    Opener: func() (io.ReadCloser, error) {
+                        return io.NopCloser(strings.NewReader(text)), nil
+                    },
+                    StreamSize: int64(len(text)),
+                }
+            }
+
+            if !msg.HasFile() {
+                continue
+            }
+
+            doc := filepath.Join(export_path, filepath.FromSlash(msg.File))
+            if !tools.FileExists(doc) {
+                log.Debug("Attached file not found", "chat", chat.ChatName(), "message", msg.Id, "file", msg.File)
+                continue
+            }
+            if err := AddTextFile(doc, vpath); err != nil {
+                log.Error("error parsing attached file", "file", doc, "err", err)
+            }
+        }
+    }
+
+    return nil
+}
+
+// AddTelegramFolder sends all the exports (result.json) under folder_path
+// to the runner
+func AddTelegramFolder(folder_path string) error {
+    log.Info("Searching Telegram exports in folder", "folder", folder_path)
+
+    found := 0
+    err := filepath.WalkDir(folder_path, func(path string, d fs.DirEntry, err error) error {
+        if err != nil {
+            log.Debug("Error walking folder", "path", path, "err", err)
+            return nil
+        }
+
+        if d.IsDir() || strings.ToLower(d.Name()) != parsers.TelegramExportFile {
+            return nil
+        }
+
+        found++
+        if err := AddTelegramExport(path); err != nil {
+            log.Error("error parsing Telegram export", "file", path, "err", err)
+        }
+        return nil
+    })
+    if err == nil && found == 0 {
+        return errors.New("no Telegram export (" + parsers.TelegramExportFile + ") found")
+    }
+    return err
+}
+
+var telegramCmd = &cobra.Command{
+    Use:   "telegram",
+    Short: "Parse Telegram Desktop channel exports",
+    Long: ascii.LogoHelp(ascii.Markdown(`
+# parse telegram
+
+Parse Telegram Desktop exports (the result.json and the attached files),
+running all rules over the message texts and over every attached document,
+including archives.
+
+The chat name is the bucket, the message timestamp is the leak date and the
+message id is the provider id, so each finding can be traced back to the
+post. Export the chats as "Machine-readable JSON", with the files.
+`)),
+    Example: `
+   - intelparser parse telegram -p ~/Downloads/Telegram\ Desktop/ChatExport_2025-02-05/
+   - intelparser parse telegram -p ~/Downloads/Telegram\ Desktop/ChatExport_2025-02-05/result.json
+   - intelparser parse telegram -p ~/Downloads/Telegram\ Desktop/ --write-elastic`,
+    PreRunE: func(cmd *cobra.Command, args []string) error {
+        var err error
+
+        if telegramCmdOptions.Path == "" {
+            return errors.New("a file or path must be specified")
+        }
+
+        if !tools.FileExists(telegramCmdOptions.Path) {
+            return errors.New("file or path is not readable")
+        }
+
+        telegramCmdOptions.Path, err = resolver.ResolveFullPath(telegramCmdOptions.Path)
+        if err != nil {
+            return err
+        }
+
+        // An slog-capable logger to use with drivers and runners
+        logger := slog.New(log.Logger)
+
+        // Configure the driver
+        telegramParser, err = parsers.NewTelegram(logger, *opts)
+        if err != nil {
+            return err
+        }
+        textParser = telegramParser.TextParser
+        parserDriver = telegramParser
+
+        // Get the runner up. Basically, all of the subcommands will use this.
+        scanRunner, err = runner.NewRunner(logger, parserDriver, *opts, scanWriters)
+        if err != nil {
+            return err
+        }
+
+        return nil
+    },
+    Run: func(cmd *cobra.Command, args []string) {
+        ft, err := tools.FileType(telegramCmdOptions.Path)
+        if err != nil {
+            log.Error("error getting path type", "err", err)
+            os.Exit(2)
+        }
+
+        log.Debug("starting parsing scanning", "path", telegramCmdOptions.Path, "type", ft)
+
+        go func() {
+            defer close(scanRunner.Files)
+
+            if ft == "file" {
+                if err = AddTelegramExport(telegramCmdOptions.Path); err != nil {
+                    log.Error("error parsing Telegram export", "err", err)
+                }
+            }else{
+                if err = AddTelegramFolder(telegramCmdOptions.Path); err != nil {
+                    log.Error("error", "err", err)
+                }
+            }
+        }()
+
+        log.Info("Starting Telegram parser")
+        status := scanRunner.Run()
+        scanRunner.Close()
+
+        printParserStatistics(status)
+
+        tools.RemoveFolder(tempFolder)
+    },
+}
+
+func init() {
+    parserCmd.AddCommand(telegramCmd)
+
+    telegramCmd.Flags().StringVarP(&telegramCmdOptions.Path, "path", "p", "", "The export result.json or a folder with the Telegram Desktop export(s)")
+}
@@ -0,0 +1,286 @@
+package cmd
+
+import (
+    "bufio"
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "log/slog"
+    "path/filepath"
+    "os"
+    "strings"
+
+    "github.com/helviojunior/intelparser/internal/ascii"
+    "github.com/helviojunior/intelparser/internal/tools"
+    "github.com/helviojunior/intelparser/pkg/archive"
+    "github.com/helviojunior/intelparser/pkg/log"
+    "github.com/helviojunior/intelparser/pkg/runner"
+    parsers "github.com/helviojunior/intelparser/pkg/runner/parsers"
+    resolver "github.com/helviojunior/gopathresolver"
+    "github.com/spf13/cobra"
+)
+
+var textParser *parsers.TextParser
+var textCmdOptions = struct {
+    Path     string
+    MetaFile string
+    NullData bool
+    Meta     parsers.TextMeta
+}{}
+
+// addTextSidecar registers the sidecar JSON (<file>.meta.json) of the file,
+// if any
+func addTextSidecar(file_path string, virtual_path string) {
+    sidecar := file_path + parsers.TextSidecarExt
+    if !tools.FileExists(sidecar) {
+        return
+    }
+
+    meta, err := parsers.ReadTextMeta(sidecar)
+    if err != nil {
+        log.Error("error reading sidecar file", "file", sidecar, "err", err)
+        return
+    }
+
+    log.Debug("Sidecar file found", "file", sidecar)
+    textParser.AddSidecar(virtual_path, meta)
+}
+
+// AddTextFile sends the file to the runner. Archives (zip, tar, gz, bz2 or
+// xz, including nested archives) have all of their members sent, and
+// mailboxes (mbox or EML) all of their messages.
+func AddTextFile(file_path string, virtual_path string) error {
+    file_name := filepath.Base(file_path)
+    vpath := filepath.ToSlash(filepath.Join(virtual_path, file_name))
+
+    addTextSidecar(file_path, vpath)
+
+    if !archive.IsArchive(file_path) {
+        item := runner.FileItem{
+            RealPath: file_path,
+            VirtualPath: vpath,
+        }
+        if !addMailboxItem(item) {
+            scanRunner.Files <- item
+        }
+        return nil
+    }
+
+    log.Info("Parsing archive file", "file", file_name)
+    return archive.Walk(file_path, virtual_path, archiveOptions(), func(e archive.Entry) error {
+        if item := runner.ArchiveFileItem(e); !addMailboxItem(item) {
+            scanRunner.Files <- item
+        }
+        return nil
+    })
+}
+
+// AddTextFolder sends all files under folder_path to the runner
+func AddTextFolder(folder_path string) error {
+    log.Info("Parsing files in folder", "folder", folder_path)
+
+    return filepath.WalkDir(folder_path, func(path string, d fs.DirEntry, err error) error {
+        if err != nil {
+            log.Debug("Error walking folder", "path", path, "err", err)
+            return nil
+        }
+
+        if d.IsDir() || strings.HasSuffix(strings.ToLower(d.Name()), parsers.TextSidecarExt) {
+            return nil
+        }
+
+        rel, _ := filepath.Rel(folder_path, filepath.Dir(path))
+        if rel == "." {
+            rel = ""
+        }
+
+        if err := AddTextFile(path, rel); err != nil {
+            log.Error("error parsing file", "file", path, "err", err)
+        }
+        return nil
+    })
+}
+
+// AddTextStdin sends the stdin content to the runner as a single document
+// or, with null_data, one document per NUL-separated block
+func AddTextStdin(r io.Reader, null_data bool) error {
+    if !null_data {
+        log.Info("Parsing stdin")
+        scanRunner.Files <- runner.FileItem{
+            VirtualPath: parsers.TextStdinName,
+            Reader: r,
+        }
+        return nil
+    }
+
+    log.Info("Parsing NUL-separated documents from stdin")
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64*1024), 256*1024*1024)
+    scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+        if i := bytes.IndexByte(data, 0); i >= 0 {
+            return i + 1, data[:i], nil
+        }
+        if atEOF && len(data) > 0 {
+            return len(data), data, nil
+        }
+        return 0, nil, nil
+    })
+
+    docs := 0
+    for scanner.Scan() {
+        if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+            continue
+        }
+        docs++
+        doc := bytes.Clone(scanner.Bytes())
+        scanRunner.Files <- runner.FileItem{
+            VirtualPath: fmt.Sprintf("%s:%d", parsers.TextStdinName, docs),
+            Reader: bytes.NewReader(doc),
+        }
+    }
+
+    return scanner.Err()
+}
+
+var textCmd = &cobra.Command{
+    Use:   "text",
+    Short: "Parse plain-text leaks (combolists, pastes and alike)",
+    Long: ascii.LogoHelp(ascii.Markdown(`
+# parse text
+
+Parse arbitrary files (file, folder or archive) with all rules, no Info.csv
+needed. Use -p - to read from stdin (e.g. in a shell pipeline).
+
+The leak information (bucket, provider, date and name) comes from the flags,
+from a JSON file (--meta-file) or from a sidecar JSON next to each file or
+archive (e.g. combo.txt.meta.json):
+
+    {"bucket": "combo-channel", "provider": "Telegram", "date": "2025-02-05"}
+
+The flags take precedence over --meta-file, and a sidecar takes precedence
+over both.
+
+Database dumps (*.sql, MySQL or PostgreSQL) are also read statement by
+statement: the rows of the INSERT ... VALUES statements and of the COPY ...
+FROM stdin blocks become credentials when the table has a user (or e-mail)
+and a password (or hash) column, named after the CREATE TABLE definition.
+The rule is "SQL Dump » <table>".
+
+CSV, TSV and JSON files (array or JSON lines) whose header (or first object)
+has at least two known columns, one of them an e-mail, or a username and a
+password, are mapped by their columns, one finding per row, instead of the
+line rules (the secret rules still run). See --columns-file for the column synonyms.
+
+Office (docx, xlsx and pptx) and PDF documents have their text extracted
+first. The findings record the sheet, page or slide they came from.
+`)),
+    Example: `
+   - intelparser parse text -p ~/Desktop/combolist.txt --bucket "Combo 2025" --date 2025-02-05
+   - intelparser parse text -p ~/Desktop/pastes/ --provider Pastebin
+   - intelparser parse text -p ~/Desktop/leak.tar.gz --meta-file ~/Desktop/leak.json --write-elastic
+   - intelparser parse text -p ~/Desktop/shop_db.sql.gz --bucket "Shop DB"
+   - zcat combolist.txt.gz | intelparser parse text -p - --bucket "Combo 2025"
+   - for f in pastes/*; do cat "$f"; printf '\\0'; done | intelparser parse text -p - --null-data
+`,
+    PreRunE: func(cmd *cobra.Command, args []string) error {
+        var err error
+
+        if textCmdOptions.Path == "" {
+            return errors.New("a file or path must be specified")
+        }
+
+        if textCmdOptions.Path != "-" {
+            if !tools.FileExists(textCmdOptions.Path) {
+                return errors.New("file or path is not readable")
+            }
+
+            textCmdOptions.Path, err = resolver.ResolveFullPath(textCmdOptions.Path)
+            if err != nil {
+                return err
+            }
+        }
+
+        meta := parsers.TextMeta{}
+        if textCmdOptions.MetaFile != "" {
+            textCmdOptions.MetaFile, err = resolver.ResolveFullPath(textCmdOptions.MetaFile)
+            if err != nil {
+                return err
+            }
+            if meta, err = parsers.ReadTextMeta(textCmdOptions.MetaFile); err != nil {
+                return err
+            }
+        }
+        meta = meta.Merge(textCmdOptions.Meta)
+
+        // An slog-capable logger to use with drivers and runners
+        logger := slog.New(log.Logger)
+
+        // Configure the driver
+        textParser, err = parsers.NewText(logger, *opts, meta)
+        if err != nil {
+            return err
+        }
+        parserDriver = textParser
+
+        // Get the runner up. Basically, all of the subcommands will use this.
+        scanRunner, err = runner.NewRunner(logger, parserDriver, *opts, scanWriters)
+        if err != nil {
+            return err
+        }
+
+        return nil
+    },
+    Run: func(cmd *cobra.Command, args []string) {
+        var ft string
+        var err error
+
+        if textCmdOptions.Path == "-" {
+            ft = "stdin"
+        }else if ft, err = tools.FileType(textCmdOptions.Path); err != nil {
+            log.Error("error getting path type", "err", err)
+            os.Exit(2)
+        }
+
+        log.Debug("starting parsing scanning", "path", textCmdOptions.Path, "type", ft)
+
+        go func() {
+            defer close(scanRunner.Files)
+
+            if ft == "stdin" {
+                if err = AddTextStdin(os.Stdin, textCmdOptions.NullData); err != nil {
+                    log.Error("error reading stdin", "err", err)
+                }
+            }else if ft == "file" {
+                if err = AddTextFile(textCmdOptions.Path, ""); err != nil {
+                    log.Error("error parsing file", "err", err)
+                }
+            }else{
+                if err = AddTextFolder(textCmdOptions.Path); err != nil {
+                    log.Error("error", "err", err)
+                }
+            }
+        }()
+
+        log.Info("Starting Text parser")
+        status := scanRunner.Run()
+        scanRunner.Close()
+
+        printParserStatistics(status)
+
+        tools.RemoveFolder(tempFolder)
+    },
+}
+
+func init() {
+    parserCmd.AddCommand(textCmd)
+
+    textCmd.Flags().StringVarP(&textCmdOptions.Path, "path", "p", "", "A file, archive or path with the file(s) to parse. Use - to read from stdin")
+    textCmd.Flags().BoolVarP(&textCmdOptions.NullData, "null-data", "z", false, "Stdin documents are separated by NUL (\\0) instead of a single document")
+    textCmd.Flags().StringVar(&textCmdOptions.MetaFile, "meta-file", "", "JSON file with the leak information (bucket, provider, date and name)")
+    textCmd.Flags().StringVar(&textCmdOptions.Meta.Bucket, "bucket", "", "Bucket of the leak (default: Unknown)")
+    textCmd.Flags().StringVar(&textCmdOptions.Meta.Provider, "provider", "", "Provider (source) of the leak (default: Text)")
+    textCmd.Flags().StringVar(&textCmdOptions.Meta.Date, "date", "", "Leak date (e.g. 2025-02-05 or 2025-02-05 10:48:28). Default: file modification time")
+    textCmd.Flags().StringVar(&textCmdOptions.Meta.Name, "name", "", "Name of the leak (default: the file path)")
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bufio"
     "encoding/json"
+    "errors"
     "fmt"
     "io"
     "os"
@@ -13,7 +14,9 @@ import (
     "time"
 
     "github.com/helviojunior/intelparser/internal/ascii"
+    "github.com/helviojunior/intelparser/internal/tools"
     "github.com/helviojunior/intelparser/pkg/database"
+    "github.com/helviojunior/intelparser/pkg/filter"
     "github.com/helviojunior/intelparser/pkg/log"
     "github.com/helviojunior/intelparser/pkg/models"
     "github.com/helviojunior/intelparser/pkg/writers"
@@ -25,6 +28,7 @@ type ConvStatus struct {
     Url int
     Email int
     Credential int
+    Secret int
     Spin string
     IsTerminal bool
 }
@@ -33,6 +37,8 @@ var dateFilter = ""
 var indexedDateFilter = ""
 var rptFilter = ""
 var filterList = []string{}
+var filterExpr *filter.Expr
+var clientFilter = ""
 var reportCmd = &cobra.Command{
     Use:   "report",
     Short: "Work with intelparser reports",
@@ -51,16 +57,22 @@ Work with intelparser reports.
             return err
         }
 
-        re := regexp.MustCompile("[^a-zA-Z0-9@-_.]")
-        s := strings.Split(rptFilter, ",")
-        for _, s1 := range s {
-            s2 := strings.ToLower(strings.Trim(s1, " "))
-            s2 = re.ReplaceAllString(s2, "")
-            if s2 != "" {
-                filterList = append(filterList, s2)
+        if filter.IsExpression(rptFilter) {
+            if filterExpr, err = filter.Parse(rptFilter); err != nil {
+                return errors.New("invalid filter expression: " + err.Error())
+            }
+        } else {
+            re := regexp.MustCompile("[^a-zA-Z0-9@_.-]")
+            s := strings.Split(rptFilter, ",")
+            for _, s1 := range s {
+                s2 := strings.ToLower(strings.Trim(s1, " "))
+                s2 = re.ReplaceAllString(s2, "")
+                if s2 != "" {
+                    filterList = append(filterList, s2)
+                }
             }
         }
-        
+
         if dateFilter != "" {
             t, err := time.Parse("2006-01-02", dateFilter)
             if err != nil {
@@ -89,6 +101,15 @@ Work with intelparser reports.
             log.Warn("Filter list: " + strings.Join(filterList, ", "))
         }
 
+        if filterExpr != nil {
+            log.Warn("Filter expression: " + filterExpr.String())
+        }
+
+        clientFilter = strings.ToLower(strings.TrimSpace(clientFilter))
+        if clientFilter != "" {
+            log.Warn("Client filter: " + clientFilter)
+        }
+
         return nil
     },
 }
@@ -96,7 +117,8 @@ Work with intelparser reports.
 func init() {
     rootCmd.AddCommand(reportCmd)
 
-    reportCmd.PersistentFlags().StringVar(&rptFilter, "filter", "", "Comma-separated terms to filter results")
+    reportCmd.PersistentFlags().StringVar(&rptFilter, "filter", "", "Comma-separated terms or a filter expression (e.g. user_domain $= \"corp.com\" and severity >= 80) to filter results")
+    reportCmd.PersistentFlags().StringVar(&clientFilter, "client", "", "Only the credentials, e-mails, URLs and secrets tagged with this client (see parse --clients-file)")
     reportCmd.PersistentFlags().StringVar(&dateFilter, "date-from", "", "Minimum date to convert. (Format: yyyy-mm-dd)")
     reportCmd.PersistentFlags().StringVar(&indexedDateFilter, "indexed-date-from", "", "Minimum date to convert. (Format: yyyy-mm-dd)")
 }
@@ -105,18 +127,19 @@ func (st *ConvStatus) Print() {
     if st.IsTerminal {
         st.Spin = ascii.GetNextSpinner(st.Spin)
 
-        fmt.Fprintf(os.Stderr, "%s\n %s converted %d: cred: %d, url: %d, email: %d\r\033[A", 
+        fmt.Fprintf(os.Stderr, "%s\n %s converted %d: cred: %d, url: %d, email: %d, secret: %d\r\033[A", 
             "                                                                        ",
             ascii.ColoredSpin(st.Spin), 
             st.Converted, 
             st.Credential, 
             st.Url, 
-            st.Email)
+            st.Email,
+            st.Secret)
 
     }else{
         log.Info("STATUS", 
             "converted", st.Converted,
-            "creds", st.Credential, "url", st.Url, "email", st.Email)
+            "creds", st.Credential, "url", st.Url, "email", st.Email, "secret", st.Secret)
     }
 } 
 
@@ -138,48 +161,164 @@ func containsFilterWord(s string) bool {
     return false
 }
 
+// isDomainTerm tells if the legacy term is a domain (e.g. client.com)
+func isDomainTerm(term string) bool {
+    return strings.Contains(term, ".") && !strings.Contains(term, "@")
+}
+
+// matchFilterTerms tells if any legacy term is in the values. The domain
+// terms match the domains (the domain or a subdomain of the term), so
+// x.com does not match box.com, and the values without domains.
+func matchFilterTerms(domains []string, values ...string) bool {
+    //If filter list is empty, always return true
+    if len(filterList) == 0 {
+        return true
+    }
+
+    for _, f := range filterList {
+        if domains != nil && isDomainTerm(f) {
+            for _, d := range domains {
+                if tools.IsDomainOrSubdomain(d, f) {
+                    return true
+                }
+            }
+            continue
+        }
+        for _, v := range values {
+            if strings.Contains(strings.ToLower(strings.Trim(v, " ")), f) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// filterFile tells if the whole file matches the filter (legacy terms in
+// its content or a file only expression, e.g. bucket = "x")
+func filterFile(file *models.File) bool {
+    if clientFilter != "" {
+        // Only the tagged entities
+        return false
+    }
+    if filterExpr != nil {
+        return filterExpr.MatchFile(file)
+    }
+    return containsFilterWord(file.Content)
+}
+
+// matchClient tells if the entity client is the --client one
+func matchClient(client string) bool {
+    return clientFilter == "" || strings.ToLower(client) == clientFilter
+}
+
+func filterCredential(file *models.File, c models.Credential) bool {
+    if !matchClient(c.Client) {
+        return false
+    }
+    if filterExpr != nil {
+        return filterExpr.Match(filter.KindCredential, file, c)
+    }
+    return matchFilterTerms([]string{c.UserDomain, c.UrlDomain}, c.Username, c.UserDomain, c.Url, c.Password, c.NearText)
+}
+
+func filterEmail(file *models.File, eml models.Email) bool {
+    if !matchClient(eml.Client) {
+        return false
+    }
+    if filterExpr != nil {
+        return filterExpr.Match(filter.KindEmail, file, eml)
+    }
+    return matchFilterTerms([]string{eml.Domain}, eml.Email, eml.NearText)
+}
+
+func filterURL(file *models.File, u models.URL) bool {
+    if !matchClient(u.Client) {
+        return false
+    }
+    if filterExpr != nil {
+        return filterExpr.Match(filter.KindURL, file, u)
+    }
+    return matchFilterTerms([]string{u.Domain}, u.Url, u.NearText)
+}
+
+func filterSecret(file *models.File, sec models.Secret) bool {
+    if !matchClient(sec.Client) {
+        return false
+    }
+    if filterExpr != nil {
+        return filterExpr.Match(filter.KindSecret, file, sec)
+    }
+    return matchFilterTerms(nil, sec.Value, sec.Type, sec.NearText)
+}
+
 func getFilteredOnly(file models.File) *models.File {
     nf := file.Clone()
 
     for _, c := range file.Credentials {
-        if containsFilterWord(c.Username) || containsFilterWord(c.Url) || containsFilterWord(c.Password) {
+        if filterCredential(&file, c) {
             nf.Credentials = append(nf.Credentials, c)
         }
     }
 
     for _, eml := range file.Emails {
-        if containsFilterWord(eml.Email) {
+        if filterEmail(&file, eml) {
             nf.Emails = append(nf.Emails, eml)
         }
     }
 
     for _, u := range file.URLs {
-        if containsFilterWord(u.Url) {
+        if filterURL(&file, u) {
             nf.URLs = append(nf.URLs, u)
         }
     }
 
-    if !containsFilterWord(nf.Content) && len(nf.Credentials) == 0 && len(nf.Emails) == 0 && len(nf.URLs) == 0 {
+    for _, sec := range file.Secrets {
+        if filterSecret(&file, sec) {
+            nf.Secrets = append(nf.Secrets, sec)
+        }
+    }
+
+    if !filterFile(nf) && len(nf.Credentials) == 0 && len(nf.Emails) == 0 && len(nf.URLs) == 0 && len(nf.Secrets) == 0 {
         return nil
     }
 
     return nf
 }
 
-func prepareSQL(fields []string) string {
+// prepareSQL returns the condition (with bound parameters) of the entity
+// table: the client, and the filter expression or the legacy terms on the
+// fields
+func prepareSQL(kind string, fields []string) (string, []interface{}) {
+    if clientFilter != "" {
+        sql, args := prepareTermsSQL(kind, fields)
+        return " and LOWER(client) = ?" + sql, append([]interface{}{clientFilter}, args...)
+    }
+    return prepareTermsSQL(kind, fields)
+}
+
+// prepareTermsSQL returns the condition of the filter expression or of the
+// legacy terms on the fields
+func prepareTermsSQL(kind string, fields []string) (string, []interface{}) {
+    if filterExpr != nil {
+        sql, args := filterExpr.SQL(kind)
+        return " and " + sql, args
+    }
+
     sql := ""
+    args := []interface{}{}
     for _, f := range fields {
         for _, w := range filterList {
             if sql != "" {
                 sql += " or "
             }
-            sql += " " + f + " like '%"+ w + "%' "
+            sql += " " + f + " like ? "
+            args = append(args, "%" + w + "%")
         }
     }
     if sql != "" {
         sql = " and (" + sql + ")"
     }
-    return sql
+    return sql, args
 }
 
 func clearScreen(){
@@ -206,7 +345,14 @@ func convertFromDbTo(fromUri string, writer writers.Writer, status *ConvStatus)
         sql_files += " AND indexed_at >= '" + opts.IndexedDateFilter.Format("2006-01-02") + "' "
     }
 
-    rows, err := conn.Model(&models.File{}).Where(sql_files).Rows()
+    var files_args []interface{}
+    if filterExpr != nil {
+        sql, args := filterExpr.SQL(filter.KindFile)
+        sql_files += " AND " + sql
+        files_args = args
+    }
+
+    rows, err := conn.Model(&models.File{}).Where(sql_files, files_args...).Rows()
     if err != nil {
         return err
     }
@@ -224,27 +370,34 @@ func convertFromDbTo(fromUri string, writer writers.Writer, status *ConvStatus)
             sql1 += " AND time >= '" + opts.DateFilter.Format("2006-01-02") + "' "
         }
 
-        sqlCred := sql1 + prepareSQL([]string{"username", "url", "password"})
-        rCred, err := conn.Model(&models.Credential{}).Where(sqlCred).Rows()
+        sqlCred, argsCred := prepareSQL(filter.KindCredential, []string{"username", "user_domain", "url", "url_domain", "password"})
+        rCred, err := conn.Model(&models.Credential{}).Where(sql1 + sqlCred, argsCred...).Rows()
         if err != nil {
             return err
         }
         defer rCred.Close()
 
-        sqlEmail := sql1 + prepareSQL([]string{"email"})
-        rEml, err := conn.Model(&models.Email{}).Where(sqlEmail).Rows()
+        sqlEmail, argsEmail := prepareSQL(filter.KindEmail, []string{"email"})
+        rEml, err := conn.Model(&models.Email{}).Where(sql1 + sqlEmail, argsEmail...).Rows()
         if err != nil {
             return err
         }
         defer rEml.Close()
 
-        sqlUrl := sql1 + prepareSQL([]string{"url"})
-        rUrl, err := conn.Model(&models.URL{}).Where(sqlUrl).Rows()
+        sqlUrl, argsUrl := prepareSQL(filter.KindURL, []string{"url"})
+        rUrl, err := conn.Model(&models.URL{}).Where(sql1 + sqlUrl, argsUrl...).Rows()
         if err != nil {
             return err
         }
         defer rUrl.Close()
 
+        sqlSecret, argsSecret := prepareSQL(filter.KindSecret, []string{"value", "type"})
+        rSecret, err := conn.Model(&models.Secret{}).Where(sql1 + sqlSecret, argsSecret...).Rows()
+        if err != nil {
+            return err
+        }
+        defer rSecret.Close()
+
         newResult := file.Clone()
 
         wg.Add(1)
@@ -254,7 +407,7 @@ func convertFromDbTo(fromUri string, writer writers.Writer, status *ConvStatus)
             var c models.Credential
             for rCred.Next() {
                 conn.ScanRows(rCred, &c)
-                if containsFilterWord(c.UserDomain) || containsFilterWord(c.Url) || containsFilterWord(c.NearText) {
+                if filterCredential(newResult, c) {
                     newResult.Credentials = append(newResult.Credentials, c)
                     status.Credential++
                 }
@@ -268,7 +421,7 @@ func convertFromDbTo(fromUri string, writer writers.Writer, status *ConvStatus)
             var eml models.Email
             for rEml.Next() {
                 conn.ScanRows(rEml, &eml)
-                if containsFilterWord(eml.Email) || containsFilterWord(eml.NearText) {
+                if filterEmail(newResult, eml) {
                     newResult.Emails = append(newResult.Emails, eml)
                     status.Email++
                 }
@@ -282,16 +435,30 @@ func convertFromDbTo(fromUri string, writer writers.Writer, status *ConvStatus)
             var u models.URL
             for rUrl.Next() {
                 conn.ScanRows(rUrl, &u)
-                if containsFilterWord(u.Url) || containsFilterWord(u.NearText) {
+                if filterURL(newResult, u) {
                     newResult.URLs = append(newResult.URLs, u)
                     status.Url++
                 }
             }
         }()
 
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            logger.Debug("Checking secrets...")
+            var sec models.Secret
+            for rSecret.Next() {
+                conn.ScanRows(rSecret, &sec)
+                if filterSecret(newResult, sec) {
+                    newResult.Secrets = append(newResult.Secrets, sec)
+                    status.Secret++
+                }
+            }
+        }()
+
         wg.Wait()
 
-        if containsFilterWord(newResult.Content) || len(newResult.Credentials) != 0 || len(newResult.Emails) != 0 || len(newResult.URLs) != 0 {
+        if filterFile(newResult) || len(newResult.Credentials) != 0 || len(newResult.Emails) != 0 || len(newResult.URLs) != 0 || len(newResult.Secrets) != 0 {
             logger.Debug("Converting file!")
             status.Converted++
             if err := writer.Write(newResult); err != nil {
@@ -344,6 +511,7 @@ func convertFromJsonlTo(from string, writer writers.Writer, status *ConvStatus)
             status.Url += len(newResult.URLs)
             status.Email += len(newResult.Emails)
             status.Credential += len(newResult.Credentials)
+            status.Secret += len(newResult.Secrets)
         }
 
         if err == io.EOF {
@@ -155,6 +155,7 @@ A --from-file (or --from-db-uri) and --elasticsearch-uri must be specified.`)),
         st += "     -> Credentials......: %s\n"
         st += "     -> URLs.............: %s\n"
         st += "     -> E-mails..........: %s\n"
+        st += "     -> Secrets..........: %s\n"
 
         log.Infof(st, 
             out.Format("15:04:05"),
@@ -162,6 +163,7 @@ A --from-file (or --from-db-uri) and --elasticsearch-uri must be specified.`)),
             tools.FormatIntComma(status.Credential),
             tools.FormatIntComma(status.Url),
             tools.FormatIntComma(status.Email),
+            tools.FormatIntComma(status.Secret),
         )
 
     },
@@ -37,8 +37,13 @@ var rootCmd = &cobra.Command{
 
 	    opts.Writer.UserPath = usr.HomeDir
 
+	    // The results are written to stdout, so everything else goes to stderr
+	    if opts.Writer.Stdout {
+	    	log.UseStderr()
+	    }
+
 	    if cmd.CalledAs() != "version" && !opts.Logging.Silence {
-			fmt.Println(ascii.Logo())
+			fmt.Fprintln(log.Output(), ascii.Logo())
 		}
 
 		if opts.Logging.Silence {
@@ -79,7 +84,7 @@ func Execute() {
         ascii.ShowCursor()
         log.Warn("interrupted, shutting down...                            ")
         ascii.ClearLine()
-        fmt.Printf("\n")
+        fmt.Fprintf(log.Output(), "\n")
         os.Exit(2)
     }()
 
@@ -102,7 +107,7 @@ func Execute() {
 		}
 
 		v += "The error was:\n\n" + fmt.Sprintf("```%s```", err)
-		fmt.Println(ascii.Markdown(v))
+		fmt.Fprintln(log.Output(), ascii.Markdown(v))
 
 		os.Exit(1)
 	}
@@ -110,7 +115,7 @@ func Execute() {
 	//Time to wait the logger flush
 	time.Sleep(time.Second/4)
     ascii.ShowCursor()
-    fmt.Printf("\n")
+    fmt.Fprintf(log.Output(), "\n")
 }
 
 func init() {
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// NormalizeDomain returns the host in lower case, without the port and the
+// leading and trailing dots
+func NormalizeDomain(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.Trim(host, ".[]")
+}
+
+// RegistrableDomain returns the registrable domain (eTLD+1) of the host,
+// based on the public suffix list embedded in golang.org/x/net/publicsuffix,
+// e.g. www.client.com.br is client.com.br. IP addresses, single labels
+// (e.g. a NetBIOS domain) and public suffixes are returned normalized.
+func RegistrableDomain(host string) string {
+	host = NormalizeDomain(host)
+	if host == "" || net.ParseIP(host) != nil || !strings.Contains(host, ".") {
+		return host
+	}
+
+	d, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return d
+}
+
+// IsDomainOrSubdomain tells if the host is the domain or one of its
+// subdomains, e.g. mail.x.com is a subdomain of x.com and box.com is not
+func IsDomainOrSubdomain(host string, domain string) bool {
+	host = NormalizeDomain(host)
+	domain = NormalizeDomain(domain)
+	if host == "" || domain == "" {
+		return false
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
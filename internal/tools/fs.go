@@ -30,16 +30,21 @@ func GetMimeType(s string) (string, error) {
 
      defer file.Close()
 
+     return GetMimeTypeFromReader(file)
+}
+
+// GetMimeTypeFromReader sniffs the mime type from the first 512 bytes of r
+func GetMimeTypeFromReader(r io.Reader) (string, error) {
      buff := make([]byte, 512)
 
      // why 512 bytes ? see http://golang.org/pkg/net/http/#DetectContentType
-     _, err = file.Read(buff)
+     n, err := io.ReadFull(r, buff)
 
-     if err != nil {
+     if err != nil && err != io.ErrUnexpectedEOF {
         return "", err
      }
 
-     filetype := http.DetectContentType(buff)
+     filetype := http.DetectContentType(buff[:n])
      if strings.Contains(filetype, ";") {
      	s1 := strings.SplitN(filetype, ";", 2)
      	if s1[0] != "" && strings.Contains(s1[0], "/") {
@@ -310,8 +315,12 @@ func GetHashFromFile(file_path string) (string, error) {
 	}
 	defer f.Close()
 
+	return GetHashFromReader(f)
+}
+
+func GetHashFromReader(r io.Reader) (string, error) {
 	h := sha1.New()
-	if _, err := io.Copy(h, f); err != nil {
+	if _, err := io.Copy(h, r); err != nil {
 		return "", err
 	}
 
@@ -326,7 +335,12 @@ func ReadTextFile(file_path string) (string, error) {
     }
     defer f.Close()
 
-    br := bufio.NewReader(f)
+    return ReadText(f)
+}
+
+// ReadText reads all the text of rd, removing the UTF-8 BOM if present
+func ReadText(rd io.Reader) (string, error) {
+    br := bufio.NewReader(rd)
     r, _, err := br.ReadRune()
     if err != nil {
         return "", err
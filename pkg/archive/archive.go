@@ -0,0 +1,610 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/extract"
+	"github.com/ulikunitz/xz"
+)
+
+// Format is an archive format, recognized by its magic bytes
+type Format string
+
+const (
+	FormatUnknown Format = ""
+	FormatZip     Format = "zip"
+	FormatTar     Format = "tar"
+	FormatGzip    Format = "gzip"
+	FormatBzip2   Format = "bzip2"
+	FormatXz      Format = "xz"
+)
+
+// ErrNotArchive is returned when the file is not a supported archive
+var ErrNotArchive = errors.New("not a supported archive (zip, tar, gzip, bzip2 or xz)")
+
+// members up to this size are kept in memory, larger ones are spooled to
+// the temp folder
+var spoolMemoryLimit int64 = 32 * 1024 * 1024
+
+var magics = []struct {
+	format Format
+	offset int
+	magic  []byte
+}{
+	{FormatZip, 0, []byte("PK\x03\x04")},
+	{FormatZip, 0, []byte("PK\x05\x06")},
+	{FormatGzip, 0, []byte("\x1f\x8b")},
+	{FormatBzip2, 0, []byte("BZh")},
+	{FormatXz, 0, []byte("\xfd7zXZ\x00")},
+	{FormatTar, 257, []byte("ustar")},
+}
+
+// Options are the archive walking options
+type Options struct {
+	// MaxDepth is how many levels of nested archives are expanded
+	// (0 expands only the outer archive)
+	MaxDepth int
+
+	// TempFolder is where large members of tar and compressed archives are
+	// spooled while they are being parsed
+	TempFolder string
+
+	// FirstNames are member names (e.g. Info.csv) emitted before the other
+	// members of a ZIP archive
+	FirstNames []string
+
+	// OnError is called when a nested archive cannot be read. If nil the
+	// walk is aborted with the error.
+	OnError func(virtual_path string, err error)
+
+	// Passwords are the candidate passwords tried on encrypted ZIP
+	// archives (ZipCrypto and AES)
+	Passwords []string
+
+	// OnPassword is called when a candidate password opens an encrypted
+	// ZIP archive
+	OnPassword func(virtual_path string, password string)
+}
+
+// Entry is a file member of an archive
+type Entry struct {
+	// VirtualPath is the member path prefixed by its archives, like
+	// outer.zip/inner.tar.gz/file.txt
+	VirtualPath string
+	Size        int64
+
+	// Opener returns a reader of the member content. It may be called
+	// more than once, until Release is called.
+	Opener  func() (io.ReadCloser, error)
+	Release func()
+}
+
+// Detect returns the archive format of the header (the first 512 bytes)
+func Detect(header []byte) Format {
+	for _, m := range magics {
+		if len(header) >= m.offset+len(m.magic) && bytes.Equal(header[m.offset:m.offset+len(m.magic)], m.magic) {
+			return m.format
+		}
+	}
+	return FormatUnknown
+}
+
+// DetectFile returns the archive format of the file
+func DetectFile(file_path string) (Format, error) {
+	f, err := os.Open(file_path)
+	if err != nil {
+		return FormatUnknown, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return FormatUnknown, err
+	}
+
+	return Detect(header[:n]), nil
+}
+
+// IsArchive returns true if the file is a supported archive. Office
+// documents (ZIP based) are not, their text is extracted by the runner.
+func IsArchive(file_path string) bool {
+	if extract.IsDocument(file_path) {
+		return false
+	}
+	format, err := DetectFile(file_path)
+	return err == nil && format != FormatUnknown
+}
+
+type walker struct {
+	opts Options
+	fn   func(Entry) error
+}
+
+// Walk calls fn for every file member of the archive, expanding nested
+// archives up to opts.MaxDepth. ZIP members are streamed straight from the
+// archive, members of sequential formats (tar and compressed files) are
+// spooled until released.
+func Walk(file_path string, virtual_path string, opts Options, fn func(Entry) error) error {
+	format, err := DetectFile(file_path)
+	if err != nil {
+		return err
+	}
+	if format == FormatUnknown {
+		return ErrNotArchive
+	}
+
+	f, err := os.Open(file_path)
+	if err != nil {
+		return err
+	}
+
+	w := &walker{
+		opts: opts,
+		fn:   fn,
+	}
+	name := path.Join(filepath.ToSlash(virtual_path), filepath.Base(file_path))
+
+	if format == FormatZip {
+		st, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		return w.walkZip(f, st.Size(), name, 0, func() { f.Close() })
+	}
+
+	defer f.Close()
+	return w.walkStream(format, bufio.NewReader(f), name, 0)
+}
+
+// Extract writes all file members of the archive (including the nested
+// ones) under dst
+func Extract(file_path string, dst string, opts Options) error {
+	root := filepath.Base(file_path) + "/"
+
+	return Walk(file_path, "", opts, func(e Entry) error {
+		defer e.Release()
+
+		rel := strings.TrimPrefix(e.VirtualPath, root)
+		fpath := filepath.Join(dst, filepath.FromSlash(rel))
+		if !strings.HasPrefix(fpath, filepath.Clean(dst)+string(os.PathSeparator)) {
+			return fmt.Errorf("illegal file path: %s", e.VirtualPath)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+			return err
+		}
+
+		r, err := e.Opener()
+		if err == ErrArchiveLocked && opts.OnError != nil {
+			opts.OnError(e.VirtualPath, err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		out, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		_, err = io.Copy(out, r)
+		return err
+	})
+}
+
+// refGroup calls onClose once the walker and all emitted entries are done
+type refGroup struct {
+	wg sync.WaitGroup
+}
+
+func newRefGroup(onClose func()) *refGroup {
+	g := &refGroup{}
+	g.wg.Add(1)
+	go func() {
+		g.wg.Wait()
+		if onClose != nil {
+			onClose()
+		}
+	}()
+	return g
+}
+
+func (g *refGroup) acquire() func() {
+	g.wg.Add(1)
+	once := sync.Once{}
+	return func() { once.Do(g.wg.Done) }
+}
+
+func (g *refGroup) done() {
+	g.wg.Done()
+}
+
+func (w *walker) walkZip(ra io.ReaderAt, size int64, virtual_path string, depth int, onClose func()) error {
+	g := newRefGroup(onClose)
+	defer g.done()
+
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return err
+	}
+
+	files := make([]*zip.File, 0, len(zr.File))
+	for _, name := range w.opts.FirstNames {
+		for _, f := range zr.File {
+			if f.Name == name {
+				files = append(files, f)
+			}
+		}
+	}
+	for _, f := range zr.File {
+		if !tools.SliceHasStr(w.opts.FirstNames, f.Name) {
+			files = append(files, f)
+		}
+	}
+
+	keys := &zipKeyring{opts: w.opts, virtual_path: virtual_path}
+
+	for _, f := range files {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		member_path := path.Join(virtual_path, f.Name)
+		password, locked := keys.password(f)
+
+		if depth < w.opts.MaxDepth && !locked && !extract.IsDocument(f.Name) {
+			format, err := zipMemberFormat(f, password)
+			if err != nil {
+				if err := w.nestedError(member_path, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if format != FormatUnknown {
+				rc, err := openZipFile(f, password)
+				if err != nil {
+					if err := w.nestedError(member_path, err); err != nil {
+						return err
+					}
+					continue
+				}
+				err = w.walkNested(format, bufio.NewReader(rc), member_path, depth+1)
+				rc.Close()
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		zf := f
+		opener := func() (io.ReadCloser, error) { return openZipFile(zf, password) }
+		if locked {
+			opener = func() (io.ReadCloser, error) { return nil, ErrArchiveLocked }
+		}
+		l := &lazySpool{w: w, inflate: opener}
+		release := g.acquire()
+		if err := w.fn(Entry{
+			VirtualPath: member_path,
+			Size:        int64(zf.UncompressedSize64),
+			Opener:      l.open,
+			Release: func() {
+				l.remove()
+				release()
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// zipKeyring keeps the password that opened the encrypted members of a ZIP
+// archive, so the other members try it first
+type zipKeyring struct {
+	opts         Options
+	virtual_path string
+	found        bool
+	current      string
+}
+
+// password returns the password of the ZIP member and whether the member
+// stays locked (no candidate password opens it)
+func (k *zipKeyring) password(f *zip.File) (string, bool) {
+	if !isEncrypted(f) {
+		return "", false
+	}
+
+	if k.found && checkPassword(f, k.current) {
+		return k.current, false
+	}
+
+	for _, pw := range k.opts.Passwords {
+		if (k.found && pw == k.current) || !verifyPassword(f, pw) {
+			continue
+		}
+		k.found = true
+		k.current = pw
+		if k.opts.OnPassword != nil {
+			k.opts.OnPassword(k.virtual_path, pw)
+		}
+		return pw, false
+	}
+
+	return "", true
+}
+
+func zipMemberFormat(f *zip.File, password string) (Format, error) {
+	rc, err := openZipFile(f, password)
+	if err != nil {
+		return FormatUnknown, err
+	}
+	defer rc.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(rc, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return FormatUnknown, err
+	}
+	return Detect(header[:n]), nil
+}
+
+// walkStream expands a sequential archive (tar or compressed file)
+func (w *walker) walkStream(format Format, r *bufio.Reader, virtual_path string, depth int) error {
+	var inner io.Reader
+
+	switch format {
+	case FormatTar:
+		return w.walkTar(r, virtual_path, depth)
+	case FormatGzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		inner = gz
+	case FormatBzip2:
+		inner = bzip2.NewReader(r)
+	case FormatXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return err
+		}
+		inner = xr
+	default:
+		return ErrNotArchive
+	}
+
+	// A compressed tar is a single archive (e.g. inner.tar.gz/file.txt)
+	br := bufio.NewReader(inner)
+	header, _ := br.Peek(512)
+	if Detect(header) == FormatTar {
+		return w.walkTar(br, virtual_path, depth)
+	}
+
+	return w.walkMember(br, -1, path.Join(virtual_path, decompressedName(virtual_path)), depth)
+}
+
+func (w *walker) walkTar(r io.Reader, virtual_path string, depth int) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := w.walkMember(tr, hdr.Size, path.Join(virtual_path, hdr.Name), depth); err != nil {
+			return err
+		}
+	}
+}
+
+// walkMember expands the member if it is a nested archive, otherwise spools
+// and emits it
+func (w *walker) walkMember(r io.Reader, size int64, virtual_path string, depth int) error {
+	br := bufio.NewReader(r)
+
+	if depth < w.opts.MaxDepth && !extract.IsDocument(virtual_path) {
+		header, _ := br.Peek(512)
+		if format := Detect(header); format != FormatUnknown {
+			return w.walkNested(format, br, virtual_path, depth+1)
+		}
+	}
+
+	s, err := w.spool(br)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		size = s.size
+	}
+
+	return w.fn(Entry{
+		VirtualPath: virtual_path,
+		Size:        size,
+		Opener:      s.open,
+		Release:     s.remove,
+	})
+}
+
+// walkNested expands a nested archive, reporting its errors through
+// opts.OnError when set
+func (w *walker) walkNested(format Format, r *bufio.Reader, virtual_path string, depth int) error {
+	var err error
+
+	if format == FormatZip {
+		// ZIP needs random access
+		var s *spool
+		if s, err = w.spool(r); err == nil {
+			var ra io.ReaderAt
+			var closer func()
+			if ra, closer, err = s.readerAt(); err == nil {
+				err = w.walkZip(ra, s.size, virtual_path, depth, closer)
+			} else {
+				s.remove()
+			}
+		}
+	} else {
+		err = w.walkStream(format, r, virtual_path, depth)
+	}
+
+	if err != nil {
+		return w.nestedError(virtual_path, err)
+	}
+	return nil
+}
+
+func (w *walker) nestedError(virtual_path string, err error) error {
+	if w.opts.OnError == nil {
+		return fmt.Errorf("%s: %w", virtual_path, err)
+	}
+	w.opts.OnError(virtual_path, err)
+	return nil
+}
+
+// decompressedName returns the name of the content of a compressed file
+func decompressedName(virtual_path string) string {
+	name := path.Base(virtual_path)
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".gz", ".gzip", ".bz2", ".xz"} {
+		if strings.HasSuffix(lower, ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	switch {
+	case strings.HasSuffix(lower, ".tgz"), strings.HasSuffix(lower, ".tbz2"), strings.HasSuffix(lower, ".txz"):
+		return name[:strings.LastIndex(name, ".")] + ".tar"
+	}
+	return name
+}
+
+// spool keeps a member of a sequential archive in memory (or in the temp
+// folder, when large) until it is released
+type spool struct {
+	data []byte
+	path string
+	size int64
+	once sync.Once
+}
+
+func (w *walker) spool(r io.Reader) (*spool, error) {
+	buf := &bytes.Buffer{}
+	n, err := io.CopyN(buf, r, spoolMemoryLimit+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if n <= spoolMemoryLimit {
+		return &spool{data: buf.Bytes(), size: n}, nil
+	}
+
+	s := &spool{path: tools.TempFileName(w.opts.TempFolder, "archive_", ".tmp")}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if s.size, err = io.Copy(f, io.MultiReader(buf, r)); err != nil {
+		os.Remove(s.path)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *spool) open() (io.ReadCloser, error) {
+	if s.path == "" {
+		return io.NopCloser(bytes.NewReader(s.data)), nil
+	}
+	return os.Open(s.path)
+}
+
+func (s *spool) readerAt() (io.ReaderAt, func(), error) {
+	if s.path == "" {
+		return bytes.NewReader(s.data), s.remove, nil
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() {
+		f.Close()
+		s.remove()
+	}, nil
+}
+
+// lazySpool inflates a ZIP member once, on its first Open, into a spool
+// that the next calls read. The parsers open the member several times (the
+// fingerprint, the header sniff, the rules and the saved content).
+type lazySpool struct {
+	w       *walker
+	inflate func() (io.ReadCloser, error)
+	mu      sync.Mutex
+	s       *spool
+	err     error
+}
+
+func (l *lazySpool) open() (io.ReadCloser, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.s == nil && l.err == nil {
+		var rc io.ReadCloser
+		if rc, l.err = l.inflate(); l.err == nil {
+			l.s, l.err = l.w.spool(rc)
+			rc.Close()
+		}
+	}
+	if l.err != nil {
+		return nil, l.err
+	}
+	return l.s.open()
+}
+
+func (l *lazySpool) remove() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.s != nil {
+		l.s.remove()
+	}
+}
+
+func (s *spool) remove() {
+	s.once.Do(func() {
+		s.data = nil
+		if s.path != "" {
+			os.Remove(s.path)
+		}
+	})
+}
@@ -0,0 +1,345 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	zipFlagEncrypted      = 0x1
+	zipFlagDataDescriptor = 0x8
+	zipMethodBzip2        = 12
+	zipMethodAES          = 99
+	zipExtraAES           = 0x9901
+	zipCryptoHeaderLen    = 12
+	zipAESVerifierLen     = 2
+	zipAESMacLen          = 10
+)
+
+// ErrArchiveLocked is returned when no candidate password opens an
+// encrypted archive
+var ErrArchiveLocked = errors.New("archive is locked: no valid password")
+
+var errZipChecksum = errors.New("zip: checksum error")
+
+// isEncrypted returns true if the ZIP member is encrypted (ZipCrypto or AES)
+func isEncrypted(f *zip.File) bool {
+	return f.Flags&zipFlagEncrypted != 0
+}
+
+// openZipFile opens the ZIP member, decrypting it with password if needed
+func openZipFile(f *zip.File, password string) (io.ReadCloser, error) {
+	if !isEncrypted(f) {
+		return f.Open()
+	}
+
+	if ae, ok := parseAESExtra(f.Extra); ok && f.Method == zipMethodAES {
+		return openAES(f, ae, password)
+	}
+	return openZipCrypto(f, password)
+}
+
+// checkPassword does the quick password check against the encryption
+// header (1 or 2 bytes, so false positives may happen)
+func checkPassword(f *zip.File, password string) bool {
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return false
+	}
+
+	if ae, ok := parseAESExtra(f.Extra); ok && f.Method == zipMethodAES {
+		salt := make([]byte, ae.saltLen())
+		verifier := make([]byte, zipAESVerifierLen)
+		if _, err := io.ReadFull(raw, salt); err != nil {
+			return false
+		}
+		if _, err := io.ReadFull(raw, verifier); err != nil {
+			return false
+		}
+		_, _, pv, err := ae.deriveKeys(password, salt)
+		return err == nil && bytes.Equal(pv, verifier)
+	}
+
+	header := make([]byte, zipCryptoHeaderLen)
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return false
+	}
+	newZipCryptoKeys(password).decrypt(header)
+	return zipCryptoCheckByte(f, header[zipCryptoHeaderLen-1])
+}
+
+// verifyPassword reads the whole ZIP member to validate the password
+func verifyPassword(f *zip.File, password string) bool {
+	if !checkPassword(f, password) {
+		return false
+	}
+
+	rc, err := openZipFile(f, password)
+	if err != nil {
+		return false
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(io.Discard, rc)
+	return err == nil
+}
+
+// decompress returns the reader of the decrypted member content
+func decompress(f *zip.File, method uint16, r io.Reader) (io.ReadCloser, error) {
+	switch method {
+	case zip.Store:
+		return io.NopCloser(r), nil
+	case zip.Deflate:
+		return flate.NewReader(r), nil
+	case zipMethodBzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	}
+	return nil, zip.ErrAlgorithm
+}
+
+// checksumReader validates the CRC32 (when known) at the end of the content
+type checksumReader struct {
+	rc    io.ReadCloser
+	hash  hash.Hash32
+	crc32 uint32
+	check bool
+}
+
+func (r *checksumReader) Read(b []byte) (int, error) {
+	n, err := r.rc.Read(b)
+	r.hash.Write(b[:n])
+	if err == io.EOF && r.check && r.hash.Sum32() != r.crc32 {
+		return n, errZipChecksum
+	}
+	return n, err
+}
+
+func (r *checksumReader) Close() error {
+	return r.rc.Close()
+}
+
+// ZipCrypto (traditional PKWARE encryption)
+
+type zipCryptoKeys [3]uint32
+
+func newZipCryptoKeys(password string) *zipCryptoKeys {
+	k := &zipCryptoKeys{0x12345678, 0x23456789, 0x34567890}
+	for _, b := range []byte(password) {
+		k.update(b)
+	}
+	return k
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ (crc >> 8)
+}
+
+func (k *zipCryptoKeys) update(b byte) {
+	k[0] = crc32Update(k[0], b)
+	k[1] = (k[1]+(k[0]&0xff))*134775813 + 1
+	k[2] = crc32Update(k[2], byte(k[1]>>24))
+}
+
+func (k *zipCryptoKeys) decrypt(buf []byte) {
+	for i := range buf {
+		temp := k[2] | 2
+		c := buf[i] ^ byte((temp*(temp^1))>>8)
+		k.update(c)
+		buf[i] = c
+	}
+}
+
+func zipCryptoCheckByte(f *zip.File, b byte) bool {
+	if f.Flags&zipFlagDataDescriptor != 0 && b == byte(f.ModifiedTime>>8) {
+		return true
+	}
+	return b == byte(f.CRC32>>24)
+}
+
+type zipCryptoReader struct {
+	r    io.Reader
+	keys *zipCryptoKeys
+}
+
+func (z *zipCryptoReader) Read(b []byte) (int, error) {
+	n, err := z.r.Read(b)
+	z.keys.decrypt(b[:n])
+	return n, err
+}
+
+func openZipCrypto(f *zip.File, password string) (io.ReadCloser, error) {
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := newZipCryptoKeys(password)
+	header := make([]byte, zipCryptoHeaderLen)
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return nil, err
+	}
+	keys.decrypt(header)
+	if !zipCryptoCheckByte(f, header[zipCryptoHeaderLen-1]) {
+		return nil, ErrArchiveLocked
+	}
+
+	rc, err := decompress(f, f.Method, &zipCryptoReader{r: raw, keys: keys})
+	if err != nil {
+		return nil, err
+	}
+	return &checksumReader{rc: rc, hash: crc32.NewIEEE(), crc32: f.CRC32, check: true}, nil
+}
+
+// WinZip AES (AE-1 and AE-2)
+
+type aesExtra struct {
+	version  uint16
+	strength byte
+	method   uint16
+}
+
+func parseAESExtra(extra []byte) (*aesExtra, bool) {
+	for len(extra) >= 4 {
+		tag := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		extra = extra[4:]
+		if size > len(extra) {
+			break
+		}
+		if tag == zipExtraAES && size >= 7 {
+			ae := &aesExtra{
+				version:  binary.LittleEndian.Uint16(extra[0:2]),
+				strength: extra[4],
+				method:   binary.LittleEndian.Uint16(extra[5:7]),
+			}
+			return ae, ae.strength >= 1 && ae.strength <= 3
+		}
+		extra = extra[size:]
+	}
+	return nil, false
+}
+
+func (ae *aesExtra) keyLen() int {
+	return 8 + 8*int(ae.strength) // 16, 24 or 32 bytes
+}
+
+func (ae *aesExtra) saltLen() int {
+	return ae.keyLen() / 2
+}
+
+func (ae *aesExtra) deriveKeys(password string, salt []byte) ([]byte, []byte, []byte, error) {
+	kl := ae.keyLen()
+	dk, err := pbkdf2.Key(sha1.New, password, salt, 1000, 2*kl+zipAESVerifierLen)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return dk[:kl], dk[kl : 2*kl], dk[2*kl:], nil
+}
+
+// aesCTR is the AES CTR mode used by WinZip: little-endian counter
+// starting at 1
+type aesCTR struct {
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	pos     int
+}
+
+func (c *aesCTR) xor(b []byte) {
+	for i := range b {
+		if c.pos == aes.BlockSize {
+			for j := range c.counter {
+				c.counter[j]++
+				if c.counter[j] != 0 {
+					break
+				}
+			}
+			c.block.Encrypt(c.stream[:], c.counter[:])
+			c.pos = 0
+		}
+		b[i] ^= c.stream[c.pos]
+		c.pos++
+	}
+}
+
+type aesReader struct {
+	r   io.Reader
+	raw io.Reader
+	ctr *aesCTR
+	mac hash.Hash
+}
+
+func (a *aesReader) Read(b []byte) (int, error) {
+	n, err := a.r.Read(b)
+	a.mac.Write(b[:n])
+	a.ctr.xor(b[:n])
+	if err == io.EOF {
+		code := make([]byte, zipAESMacLen)
+		if _, err := io.ReadFull(a.raw, code); err != nil {
+			return n, err
+		}
+		if !hmac.Equal(code, a.mac.Sum(nil)[:zipAESMacLen]) {
+			return n, errZipChecksum
+		}
+	}
+	return n, err
+}
+
+func openAES(f *zip.File, ae *aesExtra, password string) (io.ReadCloser, error) {
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, ae.saltLen())
+	verifier := make([]byte, zipAESVerifierLen)
+	if _, err := io.ReadFull(raw, salt); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(raw, verifier); err != nil {
+		return nil, err
+	}
+
+	key, authKey, pv, err := ae.deriveKeys(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(pv, verifier) {
+		return nil, ErrArchiveLocked
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	dataLen := int64(f.CompressedSize64) - int64(len(salt)) - zipAESVerifierLen - zipAESMacLen
+	if dataLen < 0 {
+		return nil, zip.ErrFormat
+	}
+
+	rc, err := decompress(f, ae.method, &aesReader{
+		r:   io.LimitReader(raw, dataLen),
+		raw: raw,
+		ctr: &aesCTR{block: block, pos: aes.BlockSize},
+		mac: hmac.New(sha1.New, authKey),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// AE-2 does not store the CRC32, the HMAC is checked instead
+	return &checksumReader{rc: rc, hash: crc32.NewIEEE(), crc32: f.CRC32, check: ae.version == 1}, nil
+}
@@ -79,6 +79,7 @@ func Connection(uri string, shouldExist, debug bool) (*gorm.DB, error) {
 		&models.URL{},
 		&models.Email{},
 		&models.Credential{},
+		&models.Secret{},
 		&Application{},
 	); err != nil {
 		return nil, err
@@ -0,0 +1,77 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Format is a document format whose text is extracted before the rules run
+type Format string
+
+const (
+	FormatUnknown Format = ""
+	FormatDocx    Format = "docx"
+	FormatXlsx    Format = "xlsx"
+	FormatPptx    Format = "pptx"
+	FormatPdf     Format = "pdf"
+)
+
+// ErrUnsupported is returned by Walk for an unknown format
+var ErrUnsupported = errors.New("unsupported document format")
+
+// Extensions of the documents, by format. The OOXML ones are ZIP files and
+// are recognized only by their extension.
+var extensions = map[string]Format{
+	".docx": FormatDocx,
+	".docm": FormatDocx,
+	".xlsx": FormatXlsx,
+	".xlsm": FormatXlsx,
+	".pptx": FormatPptx,
+	".pptm": FormatPptx,
+	".pdf":  FormatPdf,
+}
+
+// Section is the text of a part of the document: a sheet, a page or a
+// slide. Name points the findings back to it (e.g. "sheet Users" or
+// "page 3").
+type Section struct {
+	Name string
+	Text string
+}
+
+// Detect returns the document format of the file, by its extension or, for
+// PDF, by its magic bytes
+func Detect(file_name string, header []byte) Format {
+	if format, ok := extensions[strings.ToLower(filepath.Ext(file_name))]; ok {
+		return format
+	}
+	if bytes.HasPrefix(header, []byte("%PDF-")) {
+		return FormatPdf
+	}
+	return FormatUnknown
+}
+
+// IsDocument returns true if the file name is an OOXML or PDF document.
+// Archive walkers use it to keep the OOXML files (ZIP based) as files.
+func IsDocument(file_name string) bool {
+	_, ok := extensions[strings.ToLower(filepath.Ext(file_name))]
+	return ok
+}
+
+// Walk calls fn for each section of the document, in document order
+func Walk(format Format, r io.ReaderAt, size int64, fn func(Section) error) error {
+	switch format {
+	case FormatDocx:
+		return walkDocx(r, size, fn)
+	case FormatXlsx:
+		return walkXlsx(r, size, fn)
+	case FormatPptx:
+		return walkPptx(r, size, fn)
+	case FormatPdf:
+		return walkPdf(r, size, fn)
+	}
+	return ErrUnsupported
+}
@@ -0,0 +1,191 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Word parts with text, besides the numbered headers and footers (that come
+// right after the document body)
+var docxParts = []string{"document", "footnotes", "endnotes", "comments"}
+
+var (
+	docxHeaderPart = regexp.MustCompile(`^word/(header|footer)(\d*)\.xml$`)
+	pptxSlidePart  = regexp.MustCompile(`^ppt/(slides/slide|notesSlides/notesSlide)(\d+)\.xml$`)
+)
+
+// walkDocx emits the body of the document and then its headers, footers,
+// notes and comments, one section per part (e.g. "document" or "header1")
+func walkDocx(r io.ReaderAt, size int64, fn func(Section) error) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	parts := map[string]*zip.File{}
+	headers := []string{}
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(f.Name, "/")
+		if docxHeaderPart.MatchString(name) {
+			headers = append(headers, name)
+		}
+		parts[name] = f
+	}
+	sort.Strings(headers)
+
+	names := []string{"word/" + docxParts[0] + ".xml"}
+	names = append(names, headers...)
+	for _, p := range docxParts[1:] {
+		names = append(names, "word/"+p+".xml")
+	}
+
+	for _, name := range names {
+		f, ok := parts[name]
+		if !ok {
+			continue
+		}
+		text, err := partText(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := fn(Section{Name: strings.TrimSuffix(path.Base(name), ".xml"), Text: text}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkPptx emits the slides, in their number order ("slide 1"), followed by
+// their speaker notes ("notes 1")
+func walkPptx(r io.ReaderAt, size int64, fn func(Section) error) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	type slide struct {
+		name   string
+		number int
+		notes  bool
+		file   *zip.File
+	}
+
+	slides := []slide{}
+	for _, f := range zr.File {
+		m := pptxSlidePart.FindStringSubmatch(strings.TrimPrefix(f.Name, "/"))
+		if m == nil {
+			continue
+		}
+		number, _ := strconv.Atoi(m[2])
+		s := slide{name: "slide", number: number, file: f}
+		if strings.HasPrefix(m[1], "notes") {
+			s.name = "notes"
+			s.notes = true
+		}
+		slides = append(slides, s)
+	}
+	sort.Slice(slides, func(i, j int) bool {
+		if slides[i].notes != slides[j].notes {
+			return !slides[i].notes
+		}
+		return slides[i].number < slides[j].number
+	})
+
+	for _, s := range slides {
+		text, err := partText(s.file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.file.Name, err)
+		}
+		if err := fn(Section{Name: fmt.Sprintf("%s %d", s.name, s.number), Text: text}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkXlsx emits one section per sheet ("sheet <name>"), one line per row
+// with the cells (formatted values) separated by TAB
+func walkXlsx(r io.ReaderAt, size int64, fn func(Section) error) error {
+	f, err := excelize.OpenReader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for _, sheet := range f.GetSheetList() {
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			return fmt.Errorf("sheet %s: %w", sheet, err)
+		}
+
+		var text strings.Builder
+		for _, row := range rows {
+			text.WriteString(strings.Join(row, "\t"))
+			text.WriteString("\n")
+		}
+		if err := fn(Section{Name: "sheet " + sheet, Text: text.String()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// partText returns the text of a WordprocessingML or DrawingML part: the
+// text runs (w:t and a:t), with the paragraphs, breaks and tabs kept as
+// new lines and TABs
+func partText(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	var text bytes.Buffer
+	inText := false
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return text.String(), nil
+		}
+		if err != nil {
+			return text.String(), err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				// w:tab with w:val is a tab stop of the paragraph properties
+				if len(t.Attr) == 0 {
+					text.WriteByte('\t')
+				}
+			case "br", "cr":
+				text.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// walkPdf emits one section per page ("page 1"). The reader panics on some
+// malformed objects: those panics fail the document, not the whole run.
+func walkPdf(r io.ReaderAt, size int64, fn func(Section) error) error {
+	var reader *pdf.Reader
+	pages := 0
+	if err := recoverPdf(func() (err error) {
+		if reader, err = pdf.NewReader(r, size); err == nil {
+			pages = reader.NumPage()
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+
+	for i := 1; i <= pages; i++ {
+		var page pdf.Page
+		null := false
+		if err := recoverPdf(func() error {
+			page = reader.Page(i)
+			null = page.V.IsNull()
+			return nil
+		}); err != nil {
+			return fmt.Errorf("page %d: %w", i, err)
+		}
+		if null {
+			continue
+		}
+		if err := fn(Section{Name: fmt.Sprintf("page %d", i), Text: pageText(page)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recoverPdf runs fn, turning the panics of the reader into errors
+func recoverPdf(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// pageText returns the text of the page, rebuilding the lines and the
+// spaces from the position of the glyphs. Pages whose content cannot be
+// interpreted (the reader panics on some malformed streams) fall back to
+// the plain text of the page.
+func pageText(page pdf.Page) (text string) {
+	defer func() {
+		if recover() != nil {
+			text = plainText(page)
+		}
+	}()
+
+	var b strings.Builder
+	var prev *pdf.Text
+	for _, t := range page.Content().Text {
+		if t.S == "\n" || t.S == "" {
+			continue
+		}
+
+		if prev != nil {
+			size := math.Max(prev.FontSize, 1)
+			switch {
+			case math.Abs(t.Y-prev.Y) > size/2 || t.X < prev.X-size/2:
+				b.WriteByte('\n')
+			case t.X-(prev.X+prev.W) > size/4 && t.S != " " && !strings.HasSuffix(prev.S, " "):
+				b.WriteByte(' ')
+			}
+		}
+
+		b.WriteString(t.S)
+		c := t
+		prev = &c
+	}
+	if b.Len() > 0 {
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// plainText returns the text of the page with no layout
+func plainText(page pdf.Page) (text string) {
+	defer func() {
+		if recover() != nil {
+			text = ""
+		}
+	}()
+
+	text, _ = page.GetPlainText(nil)
+	return text
+}
@@ -0,0 +1,204 @@
+package filter
+
+import (
+	"sort"
+	"time"
+
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/models"
+)
+
+// Entity kinds
+const (
+	KindFile       = "file"
+	KindCredential = "credential"
+	KindEmail      = "email"
+	KindURL        = "url"
+	KindSecret     = "secret"
+)
+
+// Kinds are the entity kinds (the file is not an entity)
+var Kinds = []string{KindCredential, KindEmail, KindURL, KindSecret}
+
+type valueType int
+
+const (
+	textType valueType = iota
+	domainType
+	numberType
+	timeType
+)
+
+// field is a filterable field, with its column and its in-memory getter
+type field struct {
+	column string
+	vtype  valueType
+	get    func(file *models.File, entity interface{}) interface{}
+}
+
+// fileFields are available to every entity kind
+var fileFields = map[string]field{
+	"file_name":   {"file_name", textType, func(f *models.File, _ interface{}) interface{} { return f.FileName }},
+	"file_path":   {"file_path", textType, func(f *models.File, _ interface{}) interface{} { return f.FilePath }},
+	"name":        {"name", textType, func(f *models.File, _ interface{}) interface{} { return f.Name }},
+	"bucket":      {"bucket", textType, func(f *models.File, _ interface{}) interface{} { return f.Bucket }},
+	"provider":    {"provider", textType, func(f *models.File, _ interface{}) interface{} { return f.Provider }},
+	"mime_type":   {"mime_type", textType, func(f *models.File, _ interface{}) interface{} { return f.MIMEType }},
+	"fingerprint": {"fingerprint", textType, func(f *models.File, _ interface{}) interface{} { return f.Fingerprint }},
+	"date":        {"date", timeType, func(f *models.File, _ interface{}) interface{} { return f.Date }},
+	"indexed_at":  {"indexed_at", timeType, func(f *models.File, _ interface{}) interface{} { return f.IndexedAt }},
+	"size":        {"size", numberType, func(f *models.File, _ interface{}) interface{} { return float64(f.Size) }},
+}
+
+func cred(e interface{}) models.Credential { return e.(models.Credential) }
+func email(e interface{}) models.Email     { return e.(models.Email) }
+func url(e interface{}) models.URL         { return e.(models.URL) }
+func secret(e interface{}) models.Secret   { return e.(models.Secret) }
+
+// derivedColumns are empty on the findings parsed before they were stored,
+// so the SQL can not tell these rows apart
+var derivedColumns = map[string]bool{
+	"user_registrable_domain": true,
+	"url_registrable_domain":  true,
+	"registrable_domain":      true,
+}
+
+// registrable returns the stored registrable domain, or computes it for the
+// findings parsed before it was stored
+func registrable(stored string, domain string) string {
+	if stored != "" {
+		return stored
+	}
+	return tools.RegistrableDomain(domain)
+}
+
+var entityFields = map[string]map[string]field{
+	KindCredential: {
+		"username":    {"username", textType, func(_ *models.File, e interface{}) interface{} { return cred(e).Username }},
+		"password":    {"password", textType, func(_ *models.File, e interface{}) interface{} { return cred(e).Password }},
+		"user_domain": {"user_domain", domainType, func(_ *models.File, e interface{}) interface{} { return cred(e).UserDomain }},
+		"url":         {"url", textType, func(_ *models.File, e interface{}) interface{} { return cred(e).Url }},
+		"url_domain":  {"url_domain", domainType, func(_ *models.File, e interface{}) interface{} { return cred(e).UrlDomain }},
+		"user_registrable_domain": {"user_registrable_domain", domainType, func(_ *models.File, e interface{}) interface{} {
+			return registrable(cred(e).UserRegistrableDomain, cred(e).UserDomain)
+		}},
+		"url_registrable_domain": {"url_registrable_domain", domainType, func(_ *models.File, e interface{}) interface{} {
+			return registrable(cred(e).UrlRegistrableDomain, cred(e).UrlDomain)
+		}},
+		"cpf":      {"cpf", textType, func(_ *models.File, e interface{}) interface{} { return cred(e).CPF }},
+		"rule":     {"rule", textType, func(_ *models.File, e interface{}) interface{} { return cred(e).Rule }},
+		"severity": {"severity", numberType, func(_ *models.File, e interface{}) interface{} { return float64(cred(e).Severity) }},
+		"entropy":  {"entropy", numberType, func(_ *models.File, e interface{}) interface{} { return float64(cred(e).Entropy) }},
+		"time":     {"time", timeType, func(_ *models.File, e interface{}) interface{} { return cred(e).Time }},
+		"client":   {"client", textType, func(_ *models.File, e interface{}) interface{} { return cred(e).Client }},
+	},
+	KindEmail: {
+		"email":  {"email", textType, func(_ *models.File, e interface{}) interface{} { return email(e).Email }},
+		"domain": {"domain", domainType, func(_ *models.File, e interface{}) interface{} { return email(e).Domain }},
+		"registrable_domain": {"registrable_domain", domainType, func(_ *models.File, e interface{}) interface{} {
+			return registrable(email(e).RegistrableDomain, email(e).Domain)
+		}},
+		"time":   {"time", timeType, func(_ *models.File, e interface{}) interface{} { return email(e).Time }},
+		"client": {"client", textType, func(_ *models.File, e interface{}) interface{} { return email(e).Client }},
+	},
+	KindURL: {
+		"url":    {"url", textType, func(_ *models.File, e interface{}) interface{} { return url(e).Url }},
+		"domain": {"domain", domainType, func(_ *models.File, e interface{}) interface{} { return url(e).Domain }},
+		"registrable_domain": {"registrable_domain", domainType, func(_ *models.File, e interface{}) interface{} {
+			return registrable(url(e).RegistrableDomain, url(e).Domain)
+		}},
+		"time":   {"time", timeType, func(_ *models.File, e interface{}) interface{} { return url(e).Time }},
+		"client": {"client", textType, func(_ *models.File, e interface{}) interface{} { return url(e).Client }},
+	},
+	KindSecret: {
+		"type":    {"type", textType, func(_ *models.File, e interface{}) interface{} { return secret(e).Type }},
+		"value":   {"value", textType, func(_ *models.File, e interface{}) interface{} { return secret(e).Value }},
+		"rule":    {"rule", textType, func(_ *models.File, e interface{}) interface{} { return secret(e).Rule }},
+		"entropy": {"entropy", numberType, func(_ *models.File, e interface{}) interface{} { return float64(secret(e).Entropy) }},
+		"time":    {"time", timeType, func(_ *models.File, e interface{}) interface{} { return secret(e).Time }},
+		"client":  {"client", textType, func(_ *models.File, e interface{}) interface{} { return secret(e).Client }},
+	},
+}
+
+// lookupField returns the field of the kind, and if it is a file field
+func lookupField(kind string, name string) (field, bool, bool) {
+	if f, ok := fileFields[name]; ok {
+		return f, true, true
+	}
+	f, ok := entityFields[kind][name]
+	return f, false, ok
+}
+
+// fieldType returns the type of the field in any kind
+func fieldType(name string) (valueType, bool) {
+	if f, ok := fileFields[name]; ok {
+		return f.vtype, true
+	}
+	for _, kind := range Kinds {
+		if f, ok := entityFields[kind][name]; ok {
+			return f.vtype, true
+		}
+	}
+	return textType, false
+}
+
+// fieldKinds returns the entity kinds with the field
+func fieldKinds(name string) []string {
+	kinds := []string{}
+	for _, kind := range Kinds {
+		if _, ok := entityFields[kind][name]; ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// sharesKind tells if an entity kind has both fields
+func sharesKind(a string, b string) bool {
+	for _, kind := range Kinds {
+		_, okA := entityFields[kind][a]
+		_, okB := entityFields[kind][b]
+		if okA && okB {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldNames returns the names of all the filterable fields
+func FieldNames() []string {
+	names := map[string]bool{}
+	for n := range fileFields {
+		names[n] = true
+	}
+	for _, fields := range entityFields {
+		for n := range fields {
+			names[n] = true
+		}
+	}
+
+	list := make([]string, 0, len(names))
+	for n := range names {
+		list = append(list, n)
+	}
+	sort.Strings(list)
+	return list
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func parseTime(s string) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
@@ -0,0 +1,594 @@
+// Package filter implements the filter expressions of the report commands,
+// e.g. user_domain @= "corp.com" and severity >= 80 and not url ~ "facebook".
+//
+// An expression compiles to a parameterized SQL condition (for database
+// sources) and to an in-memory predicate (for JSON Lines sources and to
+// refine the SQL results).
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/models"
+)
+
+// Operators
+const (
+	opEqual       = "="
+	opNotEqual    = "!="
+	opRegex       = "~"
+	opNotRegex    = "!~"
+	opPrefix      = "^="
+	opSuffix      = "$="
+	opContains    = "*="
+	opDomain      = "@="
+	opGreater     = ">"
+	opGreaterEq   = ">="
+	opLess        = "<"
+	opLessEq      = "<="
+	tokAnd        = "and"
+	tokOr         = "or"
+	tokNot        = "not"
+	tokOpenParen  = "("
+	tokCloseParen = ")"
+)
+
+var textOps = []string{opEqual, opNotEqual, opRegex, opNotRegex, opPrefix, opSuffix, opContains}
+var domainOps = append([]string{opDomain}, textOps...)
+var orderOps = []string{opEqual, opNotEqual, opGreater, opGreaterEq, opLess, opLessEq}
+
+// Expr is a compiled filter expression
+type Expr struct {
+	root   node
+	source string
+	fields map[string]bool
+}
+
+type node interface{}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ n node }
+
+// cmpNode compares a field with a value
+type cmpNode struct {
+	field    string
+	op       string
+	text     string
+	number   float64
+	time     time.Time
+	dateOnly bool
+	re       *regexp.Regexp
+}
+
+// IsExpression tells a filter expression apart from a comma-separated list
+// of terms (e.g. "sec4us,webapi")
+func IsExpression(s string) bool {
+	return strings.ContainsAny(s, "=~<>!()\"'")
+}
+
+// Parse compiles the filter expression
+func Parse(s string) (*Expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty filter expression")
+	}
+
+	p := &parser{tokens: tokens, fields: map[string]bool{}}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+
+	if err := checkKinds(p.fields); err != nil {
+		return nil, err
+	}
+
+	return &Expr{root: root, source: s, fields: p.fields}, nil
+}
+
+// checkKinds returns an error when no entity kind has all the fields, as the
+// expression would match nothing (e.g. user_domain, of the credentials, and
+// domain, of the e-mails and URLs). The error names two fields with no kind
+// in common.
+func checkKinds(fields map[string]bool) error {
+	names := []string{}
+	for name := range fields {
+		if _, ok := fileFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, kind := range Kinds {
+		all := true
+		for _, name := range names {
+			if _, ok := entityFields[kind][name]; !ok {
+				all = false
+				break
+			}
+		}
+		if all {
+			return nil
+		}
+	}
+
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			if !sharesKind(a, b) {
+				return fmt.Errorf("the fields %s (%s) and %s (%s) are not of the same entity kind",
+					a, strings.Join(fieldKinds(a), ", "), b, strings.Join(fieldKinds(b), ", "))
+			}
+		}
+	}
+	return fmt.Errorf("the fields %s are not of the same entity kind", strings.Join(names, ", "))
+}
+
+// String returns the source expression
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Applies tells if the entities of the kind have all the fields of the
+// expression. An expression over e.g. severity applies only to credentials.
+func (e *Expr) Applies(kind string) bool {
+	for name := range e.fields {
+		if _, _, ok := lookupField(kind, name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// FileOnly tells if the expression has only file fields (bucket, provider,
+// date...), so it selects whole files
+func (e *Expr) FileOnly() bool {
+	return e.Applies(KindFile)
+}
+
+// Match evaluates the expression over an entity (models.Credential,
+// models.Email, models.URL or models.Secret) of the file
+func (e *Expr) Match(kind string, file *models.File, entity interface{}) bool {
+	if !e.Applies(kind) {
+		return false
+	}
+	return eval(e.root, kind, file, entity)
+}
+
+// MatchFile evaluates a file only expression over the file
+func (e *Expr) MatchFile(file *models.File) bool {
+	return e.FileOnly() && eval(e.root, KindFile, file, nil)
+}
+
+// SQL returns the condition (with bound parameters) of the entity table of
+// the kind, or of the files table with KindFile. Regular expressions have no
+// portable SQL, so the condition may select more rows than the expression,
+// never less: the rows must still be checked with Match.
+func (e *Expr) SQL(kind string) (string, []interface{}) {
+	if kind != KindFile && !e.Applies(kind) {
+		return "1=0", nil
+	}
+	return sqlOf(e.root, kind, true)
+}
+
+func eval(n node, kind string, file *models.File, entity interface{}) bool {
+	switch n := n.(type) {
+	case *andNode:
+		return eval(n.left, kind, file, entity) && eval(n.right, kind, file, entity)
+	case *orNode:
+		return eval(n.left, kind, file, entity) || eval(n.right, kind, file, entity)
+	case *notNode:
+		return !eval(n.n, kind, file, entity)
+	case *cmpNode:
+		f, _, ok := lookupField(kind, n.field)
+		if !ok {
+			return false
+		}
+		return n.compare(f.get(file, entity))
+	}
+	return false
+}
+
+func (n *cmpNode) compare(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		switch n.op {
+		case opRegex:
+			return n.re.MatchString(v)
+		case opNotRegex:
+			return !n.re.MatchString(v)
+		}
+		v = strings.ToLower(v)
+		switch n.op {
+		case opEqual:
+			return v == n.text
+		case opNotEqual:
+			return v != n.text
+		case opPrefix:
+			return strings.HasPrefix(v, n.text)
+		case opSuffix:
+			return strings.HasSuffix(v, n.text)
+		case opContains:
+			return strings.Contains(v, n.text)
+		case opDomain:
+			return tools.IsDomainOrSubdomain(v, n.text)
+		}
+	case float64:
+		return compareOrder(n.op, v, n.number)
+	case time.Time:
+		if n.dateOnly && (n.op == opEqual || n.op == opNotEqual) {
+			same := v.UTC().Format("2006-01-02") == n.time.Format("2006-01-02")
+			return same == (n.op == opEqual)
+		}
+		return compareOrder(n.op, float64(v.Sub(n.time)), 0)
+	}
+	return false
+}
+
+func compareOrder(op string, a float64, b float64) bool {
+	switch op {
+	case opEqual:
+		return a == b
+	case opNotEqual:
+		return a != b
+	case opGreater:
+		return a > b
+	case opGreaterEq:
+		return a >= b
+	case opLess:
+		return a < b
+	case opLessEq:
+		return a <= b
+	}
+	return false
+}
+
+// sqlOf returns the condition of the node. With superset, the condition
+// selects all the rows the node matches (and maybe more), otherwise only
+// rows the node matches (and maybe less), so a NOT of a subset is a
+// superset.
+func sqlOf(n node, kind string, superset bool) (string, []interface{}) {
+	switch n := n.(type) {
+	case *andNode, *orNode:
+		var left, right node
+		join := " AND "
+		if a, ok := n.(*andNode); ok {
+			left, right = a.left, a.right
+		} else {
+			o := n.(*orNode)
+			left, right = o.left, o.right
+			join = " OR "
+		}
+		ls, la := sqlOf(left, kind, superset)
+		rs, ra := sqlOf(right, kind, superset)
+		return "(" + ls + join + rs + ")", append(la, ra...)
+	case *notNode:
+		s, args := sqlOf(n.n, kind, !superset)
+		return "NOT (" + s + ")", args
+	case *cmpNode:
+		f, isFile, ok := lookupField(kind, n.field)
+		if kind == KindFile && !isFile {
+			ok = false
+		}
+		cond, args := n.sql(f)
+		if !ok || cond == "" {
+			// Unknown on SQL
+			if superset {
+				return "1=1", nil
+			}
+			return "1=0", nil
+		}
+		if isFile && kind != KindFile {
+			return "file_id IN (SELECT id FROM files WHERE " + cond + ")", args
+		}
+		if superset && derivedColumns[f.column] {
+			cond = "(" + cond + " OR COALESCE(" + f.column + ", '') = '')"
+		}
+		return cond, args
+	}
+	return "1=1", nil
+}
+
+// sql returns the condition of the comparison, or an empty string when it
+// has no portable SQL
+func (n *cmpNode) sql(f field) (string, []interface{}) {
+	col := f.column
+
+	switch f.vtype {
+	case textType, domainType:
+		switch n.op {
+		case opEqual:
+			return "LOWER(" + col + ") = ?", []interface{}{n.text}
+		case opNotEqual:
+			return "LOWER(" + col + ") <> ?", []interface{}{n.text}
+		case opPrefix:
+			return "LOWER(" + col + ") LIKE ? ESCAPE '!'", []interface{}{escapeLike(n.text) + "%"}
+		case opSuffix:
+			return "LOWER(" + col + ") LIKE ? ESCAPE '!'", []interface{}{"%" + escapeLike(n.text)}
+		case opContains:
+			return "LOWER(" + col + ") LIKE ? ESCAPE '!'", []interface{}{"%" + escapeLike(n.text) + "%"}
+		case opDomain:
+			return "(LOWER(" + col + ") = ? OR LOWER(" + col + ") LIKE ? ESCAPE '!')", []interface{}{n.text, "%." + escapeLike(n.text)}
+		}
+		return "", nil
+	case numberType:
+		op := n.op
+		if op == opNotEqual {
+			op = "<>"
+		}
+		return col + " " + op + " ?", []interface{}{n.number}
+	case timeType:
+		if n.dateOnly && (n.op == opEqual || n.op == opNotEqual) {
+			next := n.time.AddDate(0, 0, 1)
+			if n.op == opEqual {
+				return "(" + col + " >= ? AND " + col + " < ?)", []interface{}{n.time, next}
+			}
+			return "(" + col + " < ? OR " + col + " >= ?)", []interface{}{n.time, next}
+		}
+		op := n.op
+		if op == opNotEqual {
+			op = "<>"
+		}
+		return col + " " + op + " ?", []interface{}{n.time}
+	}
+	return "", nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// Lexer
+
+type token struct {
+	kind string // op, ident, string, number or the keyword/paren itself
+	text string
+	pos  int
+}
+
+func tokenize(s string) ([]token, error) {
+	tokens := []token{}
+	r := []rune(s)
+
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, token{kind: string(c), text: string(c), pos: i})
+			i++
+		case c == '"' || c == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(r) && r[i] != c; i++ {
+				if r[i] == '\\' && i+1 < len(r) && (r[i+1] == c || r[i+1] == '\\') {
+					i++
+				}
+				b.WriteRune(r[i])
+			}
+			if i >= len(r) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: "string", text: b.String(), pos: start})
+		case c == '@' && i+1 < len(r) && r[i+1] == '=':
+			tokens = append(tokens, token{kind: "op", text: opDomain, pos: i})
+			i += 2
+		case strings.ContainsRune("=!~^$*<>&|", c):
+			start := i
+			op := string(c)
+			if i+1 < len(r) && strings.ContainsRune("=~&|", r[i+1]) {
+				op += string(r[i+1])
+			}
+			i += len([]rune(op))
+			switch op {
+			case "==":
+				op = opEqual
+			case "&&":
+				tokens = append(tokens, token{kind: tokAnd, text: op, pos: start})
+				continue
+			case "||":
+				tokens = append(tokens, token{kind: tokOr, text: op, pos: start})
+				continue
+			case "!":
+				tokens = append(tokens, token{kind: tokNot, text: op, pos: start})
+				continue
+			}
+			valid := false
+			for _, o := range append(textOps, orderOps...) {
+				if o == op {
+					valid = true
+				}
+			}
+			if !valid {
+				return nil, fmt.Errorf("invalid operator %q at position %d", op, start)
+			}
+			tokens = append(tokens, token{kind: "op", text: op, pos: start})
+		default:
+			start := i
+			for i < len(r) && !unicode.IsSpace(r[i]) && !strings.ContainsRune("()\"'=!~^$*<>&|", r[i]) {
+				if r[i] == '@' && i+1 < len(r) && r[i+1] == '=' {
+					break
+				}
+				i++
+			}
+			word := string(r[start:i])
+			switch strings.ToLower(word) {
+			case tokAnd, tokOr, tokNot:
+				tokens = append(tokens, token{kind: strings.ToLower(word), text: word, pos: start})
+			default:
+				tokens = append(tokens, token{kind: "ident", text: word, pos: start})
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+// Parser
+
+type parser struct {
+	tokens []token
+	pos    int
+	fields map[string]bool
+}
+
+func (p *parser) peek() *token {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *parser) next() (*token, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, errors.New("unexpected end of filter expression")
+	}
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t != nil && t.kind == tokOr; t = p.peek() {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t != nil && t.kind == tokAnd; t = p.peek() {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.kind {
+	case tokNot:
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{n}, nil
+	case tokOpenParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if c, err := p.next(); err != nil || c.kind != tokCloseParen {
+			return nil, fmt.Errorf("missing ) of the ( at position %d", t.pos)
+		}
+		return n, nil
+	case "ident":
+		return p.parseComparison(t)
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
+
+func (p *parser) parseComparison(f *token) (node, error) {
+	name := strings.ToLower(f.text)
+	vtype, ok := fieldType(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q at position %d (use %s)", f.text, f.pos, strings.Join(FieldNames(), ", "))
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if op.kind != "op" {
+		return nil, fmt.Errorf("expected an operator after %s at position %d", f.text, op.pos)
+	}
+
+	v, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if v.kind != "string" && v.kind != "ident" {
+		return nil, fmt.Errorf("expected a value after %s at position %d", op.text, v.pos)
+	}
+
+	n := &cmpNode{field: name, op: op.text}
+	switch vtype {
+	case textType, domainType:
+		if vtype == domainType && !contains(domainOps, op.text) || vtype == textType && !contains(textOps, op.text) {
+			return nil, fmt.Errorf("operator %s is not valid for the text field %s", op.text, name)
+		}
+		n.text = strings.ToLower(v.text)
+		if op.text == opDomain {
+			n.text = tools.NormalizeDomain(v.text)
+		}
+		if op.text == opRegex || op.text == opNotRegex {
+			if n.re, err = regexp.Compile("(?i)" + v.text); err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %s", v.text, err)
+			}
+		}
+	case numberType:
+		if !contains(orderOps, op.text) {
+			return nil, fmt.Errorf("operator %s is not valid for the numeric field %s", op.text, name)
+		}
+		if n.number, err = strconv.ParseFloat(v.text, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %q for the field %s", v.text, name)
+		}
+	case timeType:
+		if !contains(orderOps, op.text) {
+			return nil, fmt.Errorf("operator %s is not valid for the date field %s", op.text, name)
+		}
+		if n.time, err = parseTime(v.text); err != nil {
+			return nil, fmt.Errorf("invalid date %q for the field %s (e.g. 2025-02-05 or 2025-02-05 10:48:28)", v.text, name)
+		}
+		n.dateOnly = len(v.text) == len("2006-01-02")
+	}
+
+	p.fields[name] = true
+	return n, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, i := range list {
+		if i == s {
+			return true
+		}
+	}
+	return false
+}
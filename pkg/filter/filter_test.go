@@ -0,0 +1,240 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/helviojunior/intelparser/pkg/models"
+)
+
+var (
+	testTime = time.Date(2025, 2, 5, 10, 0, 0, 0, time.UTC)
+	testDay  = time.Date(2025, 2, 5, 0, 0, 0, 0, time.UTC)
+
+	testFile = &models.File{
+		FileName: "leak.txt",
+		Bucket:   "Telegram » Channel",
+		Date:     time.Date(2025, 2, 5, 10, 48, 28, 0, time.UTC),
+		Size:     1024,
+	}
+
+	testCredential = models.Credential{
+		Username:   "Alice@Corp.com",
+		Password:   "P@ss_100%",
+		UserDomain: "mail.corp.com",
+		Url:        "https://www.facebook.com/login",
+		UrlDomain:  "www.facebook.com",
+		Severity:   90,
+		Entropy:    3.5,
+		Time:       testTime,
+	}
+)
+
+// TestOperators runs each operator through Match and SQL over a credential
+func TestOperators(t *testing.T) {
+	tests := []struct {
+		expr  string
+		match bool
+		sql   string
+		args  []interface{}
+	}{
+		{`username = "ALICE@corp.com"`, true, "LOWER(username) = ?", []interface{}{"alice@corp.com"}},
+		{`username == "bob"`, false, "LOWER(username) = ?", []interface{}{"bob"}},
+		{`username != "bob"`, true, "LOWER(username) <> ?", []interface{}{"bob"}},
+		{`username ^= "ALICE"`, true, "LOWER(username) LIKE ? ESCAPE '!'", []interface{}{"alice%"}},
+		{`username $= "corp.com"`, true, "LOWER(username) LIKE ? ESCAPE '!'", []interface{}{"%corp.com"}},
+		{`password *= "_100%"`, true, "LOWER(password) LIKE ? ESCAPE '!'", []interface{}{"%!_100!%%"}},
+		{`password *= "x100"`, false, "LOWER(password) LIKE ? ESCAPE '!'", []interface{}{"%x100%"}},
+		// regular expressions have no SQL: the condition is a superset
+		{`url ~ "FACE.ook"`, true, "1=1", nil},
+		{`url !~ "facebook"`, false, "1=1", nil},
+		{`user_domain @= "corp.com"`, true, "(LOWER(user_domain) = ? OR LOWER(user_domain) LIKE ? ESCAPE '!')", []interface{}{"corp.com", "%.corp.com"}},
+		{`user_domain @= "orp.com"`, false, "(LOWER(user_domain) = ? OR LOWER(user_domain) LIKE ? ESCAPE '!')", []interface{}{"orp.com", "%.orp.com"}},
+		{`severity > 80`, true, "severity > ?", []interface{}{80.0}},
+		{`severity >= 90`, true, "severity >= ?", []interface{}{90.0}},
+		{`severity < 90`, false, "severity < ?", []interface{}{90.0}},
+		{`severity <= 90`, true, "severity <= ?", []interface{}{90.0}},
+		{`entropy = 3.5`, true, "entropy = ?", []interface{}{3.5}},
+		{`severity != 90`, false, "severity <> ?", []interface{}{90.0}},
+		{`time = 2025-02-05`, true, "(time >= ? AND time < ?)", []interface{}{testDay, testDay.AddDate(0, 0, 1)}},
+		{`time != 2025-02-05`, false, "(time < ? OR time >= ?)", []interface{}{testDay, testDay.AddDate(0, 0, 1)}},
+		{`time < "2025-02-05 10:00:00"`, false, "time < ?", []interface{}{testTime}},
+		// file fields select the entities of the matching files
+		{`date >= "2025-02-05 10:48:28"`, true, "file_id IN (SELECT id FROM files WHERE date >= ?)", []interface{}{time.Date(2025, 2, 5, 10, 48, 28, 0, time.UTC)}},
+		{`size > 2048`, false, "file_id IN (SELECT id FROM files WHERE size > ?)", []interface{}{2048.0}},
+		{`bucket ~ "telegram"`, true, "1=1", nil},
+		{`severity >= 80 and username ^= "alice"`, true, "(severity >= ? AND LOWER(username) LIKE ? ESCAPE '!')", []interface{}{80.0, "alice%"}},
+		{`severity < 80 or username ^= "bob"`, false, "(severity < ? OR LOWER(username) LIKE ? ESCAPE '!')", []interface{}{80.0, "bob%"}},
+		{`severity >= 80 && (username ^= "bob" || cpf = "")`, true, "(severity >= ? AND (LOWER(username) LIKE ? ESCAPE '!' OR LOWER(cpf) = ?))", []interface{}{80.0, "bob%", ""}},
+		// under not, the regular expressions select no rows, so the NOT of
+		// the condition is still a superset
+		{`not url ~ "facebook"`, false, "NOT (1=0)", nil},
+		{`! url ~ "twitter"`, true, "NOT (1=0)", nil},
+		{`not (url ~ "x" or severity < 50)`, true, "NOT ((1=0 OR severity < ?))", []interface{}{50.0}},
+		{`not not url ~ "facebook"`, true, "NOT (NOT (1=1))", nil},
+		// the derived columns are empty on old rows: the superset keeps them,
+		// the subset (under not) does not
+		{`user_registrable_domain @= "corp.com"`, true, "((LOWER(user_registrable_domain) = ? OR LOWER(user_registrable_domain) LIKE ? ESCAPE '!') OR COALESCE(user_registrable_domain, '') = '')", []interface{}{"corp.com", "%.corp.com"}},
+		{`not user_registrable_domain @= "corp.com"`, false, "NOT ((LOWER(user_registrable_domain) = ? OR LOWER(user_registrable_domain) LIKE ? ESCAPE '!'))", []interface{}{"corp.com", "%.corp.com"}},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+
+		if got := e.Match(KindCredential, testFile, testCredential); got != tt.match {
+			t.Errorf("Match(%q) = %v, want %v", tt.expr, got, tt.match)
+		}
+
+		sql, args := e.SQL(KindCredential)
+		if sql != tt.sql {
+			t.Errorf("SQL(%q) = %q, want %q", tt.expr, sql, tt.sql)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("SQL(%q) args = %#v, want %#v", tt.expr, args, tt.args)
+		}
+	}
+}
+
+// TestKinds checks that an expression selects only the kinds with all of
+// its fields
+func TestKinds(t *testing.T) {
+	tests := []struct {
+		expr  string
+		kinds []string
+	}{
+		{`severity >= 80`, []string{KindCredential}},
+		{`domain @= "corp.com"`, []string{KindEmail, KindURL}},
+		{`client = "acme" and time >= 2025-01-01`, []string{KindCredential, KindEmail, KindURL, KindSecret}},
+		{`bucket *= "telegram"`, []string{KindCredential, KindEmail, KindURL, KindSecret}},
+		{`rule ^= "secret" and value != ""`, []string{KindSecret}},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+
+		for _, kind := range Kinds {
+			want := false
+			for _, k := range tt.kinds {
+				want = want || k == kind
+			}
+			if got := e.Applies(kind); got != want {
+				t.Errorf("Applies(%q, %s) = %v, want %v", tt.expr, kind, got, want)
+			}
+			if sql, _ := e.SQL(kind); !want && sql != "1=0" {
+				t.Errorf("SQL(%q, %s) = %q, want 1=0", tt.expr, kind, sql)
+			}
+		}
+	}
+
+	e, _ := Parse(`severity >= 80`)
+	if e.Match(KindEmail, testFile, models.Email{Email: "alice@corp.com"}) {
+		t.Errorf("Match(%q) of an e-mail = true, want false", e)
+	}
+}
+
+// TestFileOnly checks the expressions over the file fields only
+func TestFileOnly(t *testing.T) {
+	e, err := Parse(`bucket *= "TELEGRAM" and size <= 1024`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !e.FileOnly() || !e.MatchFile(testFile) {
+		t.Errorf("FileOnly = %v, MatchFile = %v, want true", e.FileOnly(), e.MatchFile(testFile))
+	}
+
+	sql, args := e.SQL(KindFile)
+	want := "(LOWER(bucket) LIKE ? ESCAPE '!' AND size <= ?)"
+	if sql != want || !reflect.DeepEqual(args, []interface{}{"%telegram%", 1024.0}) {
+		t.Errorf("SQL(file) = %q %#v, want %q", sql, args, want)
+	}
+
+	e, _ = Parse(`bucket *= "telegram" and severity > 1`)
+	if e.FileOnly() || e.MatchFile(testFile) {
+		t.Errorf("FileOnly(%q) = true, want false", e)
+	}
+}
+
+// TestParseErrors checks the rejected expressions
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{``, "empty filter expression"},
+		{`foo = "x"`, `unknown field "foo"`},
+		{`severity ~ "9"`, "not valid for the numeric field severity"},
+		{`username > "a"`, "not valid for the text field username"},
+		{`username @= "corp.com"`, "not valid for the text field username"},
+		{`severity >= high`, `invalid number "high"`},
+		{`date >= yesterday`, `invalid date "yesterday"`},
+		{`url ~ "("`, "invalid regular expression"},
+		{`username = "alice`, "unterminated string"},
+		{`username =~ "a"`, `invalid operator "=~"`},
+		{`username =< "a"`, "expected a value after ="},
+		{`(severity > 1`, "missing )"},
+		{`severity > 1 severity`, `unexpected "severity"`},
+		{`severity >`, "unexpected end"},
+		{`user_domain @= "corp.com" or domain @= "corp.com"`, "domain (email, url) and user_domain (credential) are not of the same entity kind"},
+		{`severity > 1 and not value = "x"`, "severity (credential) and value (secret)"},
+		{`rule = "x" and url = "y" and domain = "z"`, "domain (email, url) and rule (credential, secret)"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if err == nil {
+			t.Errorf("Parse(%q) = nil error, want %q", tt.expr, tt.err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%q) = %q, want %q", tt.expr, err, tt.err)
+		}
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"corp.com", "corp.com"},
+		{"100%", "100!%"},
+		{"a_b", "a!_b"},
+		{"hi!", "hi!!"},
+		{"!%_", "!!!%!_"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeLike(tt.in); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsExpression(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"sec4us,webapi", false},
+		{"client.com", false},
+		{`user_domain @= "corp.com"`, true},
+		{"severity>80", true},
+		{"(bucket ~ x)", true},
+	}
+
+	for _, tt := range tests {
+		if got := IsExpression(tt.in); got != tt.want {
+			t.Errorf("IsExpression(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -3,9 +3,10 @@ package log
 import (
     "os"
     "fmt"
-    //"io"
+    "io"
     "bufio"
     "runtime"
+    "sync"
     
 
     "github.com/helviojunior/intelparser/internal/ascii"
@@ -23,6 +24,10 @@ var Logger *LLogger
 var logFilePath string
 var bl string
 
+// Where the log lines are written (stdout, unless UseStderr is called)
+var output io.Writer = os.Stdout
+var outputMutex sync.Mutex
+
 func init() {
     styles := log.DefaultStyles()
     styles.Keys["err"] = lipgloss.NewStyle().Foreground(lipgloss.Color("204"))
@@ -38,16 +43,14 @@ func init() {
     r, w, _ := os.Pipe()
 
     go func() {
-        f := bufio.NewWriter(os.Stdout)
-        defer f.Flush()
-        
         scanner := bufio.NewScanner(r)
         for scanner.Scan() {
             s := scanner.Text() + bl
             clearLine()
             //os.Stdout.WriteString(s)
-            f.WriteString(s)
-            f.Flush()
+            outputMutex.Lock()
+            io.WriteString(output, s)
+            outputMutex.Unlock()
             writeTextToFile(ascii.ScapeAnsi(s))
         }
     }()
@@ -61,6 +64,21 @@ func init() {
 
 }
 
+// UseStderr sends the log lines (and the other human messages) to stderr,
+// keeping stdout clean for the results (e.g. in a shell pipeline)
+func UseStderr() {
+    outputMutex.Lock()
+    defer outputMutex.Unlock()
+    output = os.Stderr
+}
+
+// Output returns where the human messages (logo, tables, etc.) are written
+func Output() io.Writer {
+    outputMutex.Lock()
+    defer outputMutex.Unlock()
+    return output
+}
+
 func SetOutFile(destination string) error{
     logFilePath = destination
 
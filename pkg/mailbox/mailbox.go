@@ -0,0 +1,406 @@
+package mailbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// MessageFile is the file name of the message text (the main headers and
+// the decoded bodies), under the message virtual path
+const MessageFile = "message.txt"
+
+// Extensions of the mailbox files. Other files are recognized by their
+// content.
+var Extensions = []string{".eml", ".mbox", ".mbx"}
+
+// Extensions of the attachments handled as text, besides the text/* media
+// types
+var textExtensions = []string{
+	".txt", ".csv", ".tsv", ".json", ".jsonl", ".log", ".sql", ".xml", ".html", ".htm",
+	".md", ".ini", ".conf", ".cfg", ".config", ".env", ".yml", ".yaml", ".properties",
+	".js", ".py", ".sh", ".ps1", ".bat", ".cmd", ".php", ".pem", ".key",
+}
+
+// Header fields of the message that make a file a mailbox (along with From)
+var messageFields = []string{
+	"date", "subject", "message-id", "received", "mime-version",
+	"return-path", "delivered-to", "to",
+}
+
+// multipart nesting limit
+const maxDepth = 10
+
+// Header is the origin of a message
+type Header struct {
+	From      string
+	To        string
+	Subject   string
+	MessageId string
+	Date      time.Time
+}
+
+// Part is a decoded text of the message: the message text or a text
+// attachment
+type Part struct {
+	Name    string
+	Content []byte
+}
+
+// Message is one message of the mailbox
+type Message struct {
+	// Index is the position of the message in the mailbox, from 1
+	Index  int
+	Header Header
+	Parts  []Part
+}
+
+// Detect tells if the content (the first KBs of the file) is a mailbox: an
+// mbox ("From " line) or an RFC 822 message, whose first lines are header
+// fields with From and other message fields
+func Detect(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("From ")) {
+		line, _, _ := bytes.Cut(data, []byte("\n"))
+		return len(bytes.Fields(line)) >= 3
+	}
+
+	from := false
+	fields := 0
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			break
+		}
+		if i == len(lines)-1 {
+			// truncated line
+			break
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if i == 0 {
+				return false
+			}
+			continue
+		}
+
+		name, _, ok := strings.Cut(line, ":")
+		if !ok || name == "" || strings.IndexFunc(name, func(r rune) bool {
+			return !(r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+		}) >= 0 {
+			return false
+		}
+
+		name = strings.ToLower(name)
+		if name == "from" {
+			from = true
+		}
+		for _, f := range messageFields {
+			if name == f {
+				fields++
+			}
+		}
+	}
+	return from && fields >= 2
+}
+
+// IsMailbox tells if the file is a mailbox, by its extension or by its
+// content
+func IsMailbox(file_name string, open func() (io.ReadCloser, error)) bool {
+	ext := strings.ToLower(filepath.Ext(file_name))
+	for _, e := range Extensions {
+		if ext == e {
+			return true
+		}
+	}
+
+	r, err := open()
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+
+	header := make([]byte, 4096)
+	n, _ := io.ReadFull(r, header)
+	return Detect(header[:n])
+}
+
+// Walk calls fn for each message of the mailbox: the messages of an mbox
+// (split by the "From " lines) or the single message of an EML file
+func Walk(r io.Reader, fn func(Message) error) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	if head, _ := br.Peek(5); string(head) != "From " {
+		raw, err := io.ReadAll(br)
+		if err != nil {
+			return err
+		}
+		msg := ParseMessage(raw)
+		msg.Index = 1
+		return fn(msg)
+	}
+
+	var buf bytes.Buffer
+	index := 0
+	flush := func() error {
+		if len(bytes.TrimSpace(buf.Bytes())) == 0 {
+			buf.Reset()
+			return nil
+		}
+		index++
+		msg := ParseMessage(bytes.Clone(buf.Bytes()))
+		msg.Index = index
+		buf.Reset()
+		return fn(msg)
+	}
+
+	blank := true
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if blank && bytes.HasPrefix(line, []byte("From ")) {
+				// The From_ line starts the next message, and is not part of it
+				if err := flush(); err != nil {
+					return err
+				}
+			} else {
+				// mboxrd: the body lines starting with ">From " had one ">" added
+				if q := bytes.TrimLeft(line, ">"); len(q) < len(line) && bytes.HasPrefix(q, []byte("From ")) {
+					line = line[1:]
+				}
+				buf.Write(line)
+			}
+			blank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ParseMessage decodes the message: the header fields (RFC 2047 encoded
+// words), the quoted-printable and base64 bodies and the text attachments.
+// A message that cannot be parsed is returned as is.
+func ParseMessage(raw []byte) Message {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return Message{
+			Parts: []Part{{Name: MessageFile, Content: raw}},
+		}
+	}
+
+	p := &parser{
+		text:  &bytes.Buffer{},
+		names: map[string]bool{},
+	}
+	header := p.header(m.Header)
+	p.walk(textproto.MIMEHeader(m.Header), m.Body, 0)
+
+	return Message{
+		Header: header,
+		Parts:  append([]Part{{Name: MessageFile, Content: p.text.Bytes()}}, p.attachments...),
+	}
+}
+
+// parser collects the texts of one message
+type parser struct {
+	text        *bytes.Buffer
+	attachments []Part
+	names       map[string]bool
+}
+
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return nil, err
+		}
+		return enc.NewDecoder().Reader(input), nil
+	},
+}
+
+// decodeHeader decodes the RFC 2047 encoded words of the field value
+func decodeHeader(v string) string {
+	if s, err := wordDecoder.DecodeHeader(v); err == nil {
+		return strings.TrimSpace(s)
+	}
+	return strings.TrimSpace(v)
+}
+
+// header returns the origin of the message, and writes its main fields
+// on the message text, so the rules see them too
+func (p *parser) header(h mail.Header) Header {
+	header := Header{
+		From:      decodeHeader(h.Get("From")),
+		To:        decodeHeader(h.Get("To")),
+		Subject:   decodeHeader(h.Get("Subject")),
+		MessageId: strings.Trim(h.Get("Message-Id"), "<> \t"),
+	}
+	if dt, err := mail.ParseDate(h.Get("Date")); err == nil {
+		header.Date = dt
+	}
+
+	for _, f := range []string{"From", "To", "Cc", "Reply-To", "Subject", "Date"} {
+		if v := decodeHeader(h.Get(f)); v != "" {
+			fmt.Fprintf(p.text, "%s: %s\n", f, v)
+		}
+	}
+	return header
+}
+
+// walk decodes the part: the multipart ones are walked, the text ones go
+// to the message text (or are attachments, when they have a file name)
+// and the binary ones are skipped
+func (p *parser) walk(h textproto.MIMEHeader, body io.Reader, depth int) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || mediaType == "" {
+		mediaType = "text/plain"
+		params = map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxDepth || params["boundary"] == "" {
+			return
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err != nil {
+				return
+			}
+			p.walk(part.Header, part, depth+1)
+		}
+	}
+
+	content := decodeTransfer(h.Get("Content-Transfer-Encoding"), body)
+
+	if mediaType == "message/rfc822" && depth < maxDepth {
+		inner := ParseMessage(content)
+		p.text.WriteString("\n")
+		p.text.Write(inner.Parts[0].Content)
+		for _, a := range inner.Parts[1:] {
+			p.attach(a.Name, a.Content)
+		}
+		return
+	}
+
+	name := attachmentName(h, params)
+	if !isText(mediaType, name) {
+		return
+	}
+	content = decodeCharset(params["charset"], content)
+
+	if name != "" {
+		p.attach(name, content)
+		return
+	}
+	p.text.WriteString("\n")
+	p.text.Write(content)
+}
+
+// attach adds the text attachment, with a unique file name
+func (p *parser) attach(name string, content []byte) {
+	n := name
+	for i := 2; p.names[strings.ToLower(n)] || strings.EqualFold(n, MessageFile); i++ {
+		n = fmt.Sprintf("%d_%s", i, name)
+	}
+	p.names[strings.ToLower(n)] = true
+	p.attachments = append(p.attachments, Part{Name: n, Content: content})
+}
+
+// attachmentName returns the file name of the part (Content-Disposition
+// filename or Content-Type name), with no folders
+func attachmentName(h textproto.MIMEHeader, params map[string]string) string {
+	name := ""
+	if _, dp, err := mime.ParseMediaType(h.Get("Content-Disposition")); err == nil {
+		name = dp["filename"]
+	}
+	if name == "" {
+		name = params["name"]
+	}
+	name = decodeHeader(name)
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// isText tells if the part is text, by its media type or file name
+func isText(mediaType string, name string) bool {
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/sql", "application/csv",
+		"application/x-sh", "application/javascript", "application/x-yaml":
+		return true
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range textExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeTransfer decodes the base64 and quoted-printable bodies. Broken
+// bodies are decoded up to the first error.
+func decodeTransfer(encoding string, body io.Reader) []byte {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		data, _ := io.ReadAll(body)
+		data = bytes.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+				return -1
+			}
+			return r
+		}, data)
+		dst := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+		n, err := base64.StdEncoding.Decode(dst, data)
+		if err != nil && n == 0 {
+			n, _ = base64.RawStdEncoding.Decode(dst, bytes.TrimRight(data, "="))
+		}
+		return dst[:n]
+	case "quoted-printable":
+		data, _ := io.ReadAll(quotedprintable.NewReader(body))
+		return data
+	}
+
+	data, _ := io.ReadAll(body)
+	return data
+}
+
+// decodeCharset converts the text to UTF-8
+func decodeCharset(charset string, content []byte) []byte {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return content
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return content
+	}
+	if data, err := enc.NewDecoder().Bytes(content); err == nil {
+		return data
+	}
+	return content
+}
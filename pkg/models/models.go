@@ -31,6 +31,11 @@ type File struct {
 
 	Content 		  	  string 	`json:"content"`
 
+	// Origin of the files extracted from mailboxes (message text and
+	// attachments)
+	MailFrom 		  	  string 	`json:"mail_from"`
+	MailSubject 	  	  string 	`json:"mail_subject"`
+
 	// Failed flag set if the result should be considered failed
 	Failed       		  bool   	`json:"failed"`
 	FailedReason 		  string 	`json:"failed_reason"`
@@ -38,6 +43,7 @@ type File struct {
 	Credentials []Credential `json:"credentials" gorm:"constraint:OnDelete:CASCADE"`
 	Emails      []Email      `json:"emails" gorm:"constraint:OnDelete:CASCADE"`
 	URLs        []URL        `json:"urls" gorm:"constraint:OnDelete:CASCADE"`
+	Secrets     []Secret     `json:"secrets" gorm:"constraint:OnDelete:CASCADE"`
 
 }
 
@@ -49,8 +55,18 @@ type URL struct {
 	Time        time.Time   `json:"time"`
 
 	Domain		string      `json:"domain"`
+	RegistrableDomain string `json:"registrable_domain"` //eTLD+1 of the domain
 	Url         string      `json:"url"`
 
+	Client      string      `json:"client" gorm:"index"` //Client (or case) the URL belongs to
+
+	StartLine   int         `json:"start_line"`
+	EndLine     int         `json:"end_line"`
+	StartColumn int         `json:"start_column"`
+	EndColumn   int         `json:"end_column"`
+	Offset      int         `json:"offset"` //Absolute byte offset in the file
+	Location    string      `json:"location"` //Sheet, page or slide of the document, when extracted from one
+
 	NearText    string 		`json:"near_text"`
 }
 
@@ -61,8 +77,18 @@ type Email struct {
 	Time        time.Time   `json:"time"`
 
 	Domain		string      `json:"domain"`
+	RegistrableDomain string `json:"registrable_domain"` //eTLD+1 of the domain
 	Email       string      `json:"email"`
 
+	Client      string      `json:"client" gorm:"index"` //Client (or case) the e-mail belongs to
+
+	StartLine   int         `json:"start_line"`
+	EndLine     int         `json:"end_line"`
+	StartColumn int         `json:"start_column"`
+	EndColumn   int         `json:"end_column"`
+	Offset      int         `json:"offset"` //Absolute byte offset in the file
+	Location    string      `json:"location"` //Sheet, page or slide of the document, when extracted from one
+
 	NearText    string 		`json:"near_text"`
 }
 
@@ -74,6 +100,7 @@ type Credential struct {
 	Time        time.Time   `json:"time"`
 
 	UserDomain	string      `json:"user_domain"`
+	UserRegistrableDomain string `json:"user_registrable_domain"` //eTLD+1 of the user domain
 	Username    string      `json:"username"`
 	Password    string      `json:"password"`
 
@@ -81,10 +108,43 @@ type Credential struct {
 
 	Url         string      `json:"url"`
 	UrlDomain	string      `json:"url_domain"`
+	UrlRegistrableDomain string `json:"url_registrable_domain"` //eTLD+1 of the URL domain
+
+	Client      string      `json:"client" gorm:"index"` //Client (or case) the credential belongs to
 
 	Severity    int 	    `json:"severity"`
 	Entropy     float32     `json:"entropy"`
 
+	StartLine   int         `json:"start_line"`
+	EndLine     int         `json:"end_line"`
+	StartColumn int         `json:"start_column"`
+	EndColumn   int         `json:"end_column"`
+	Offset      int         `json:"offset"` //Absolute byte offset in the file
+	Location    string      `json:"location"` //Sheet, page or slide of the document, when extracted from one
+
+	NearText    string 		`json:"near_text"`
+}
+
+type Secret struct {
+	ID       uint `json:"id" gorm:"primarykey"`
+	FileID   uint `json:"file_id" gorm:"index:idx_secret"`
+
+	Rule        string      `json:"rule"`
+	Time        time.Time   `json:"time"`
+
+	Type        string      `json:"type"` //aws_access_key, github_token, jwt, ...
+	Value       string      `json:"value"`
+	Entropy     float32     `json:"entropy"`
+
+	Client      string      `json:"client" gorm:"index"` //Client (or case) the secret belongs to
+
+	StartLine   int         `json:"start_line"`
+	EndLine     int         `json:"end_line"`
+	StartColumn int         `json:"start_column"`
+	EndColumn   int         `json:"end_column"`
+	Offset      int         `json:"offset"` //Absolute byte offset in the file
+	Location    string      `json:"location"` //Sheet, page or slide of the document, when extracted from one
+
 	NearText    string 		`json:"near_text"`
 }
 
@@ -100,6 +160,14 @@ type Finding struct {
     StartColumn int
     EndColumn   int
 
+    // Offset is the byte offset of the match (absolute in the file after
+    // DetectFile)
+    Offset      int
+
+    // Location is the sheet, page or slide of the document the text was
+    // extracted from (e.g. "sheet Users" or "page 3")
+    Location    string
+
     Line string `json:"-"`
 
     Match string
@@ -128,6 +196,10 @@ type Finding struct {
     Credential Credential
     Email Email
     Url URL
+
+    // SecretEntity is the API token, key or connection string extracted by
+    // the rule (not to be confused with the matched Secret string)
+    SecretEntity Secret
 }
 
 
@@ -146,6 +218,8 @@ func (file File) Clone() *File {
 		MIMEType 			: file.MIMEType,
 		Fingerprint 		: file.Fingerprint,
 		Content 			: file.Content,
+		MailFrom 			: file.MailFrom,
+		MailSubject 		: file.MailSubject,
 
 		//Credentials 		: make([]Credential{}),
 		//Emails 				: make([]Email{}),
@@ -170,6 +244,12 @@ func (file File) MarshalJSON() ([]byte, error) {
 		MIMEType    		  string    `json:"mime_type"`
 		Fingerprint	    	  string   	`json:"fingerprint"`
 		Content 			  string   	`json:"content,omitempty"`
+		MailFrom 			  string   	`json:"mail_from,omitempty"`
+		MailSubject 		  string   	`json:"mail_subject,omitempty"`
+		Credentials 		  []Credential `json:"credentials,omitempty"`
+		Emails 				  []Email 	`json:"emails,omitempty"`
+		URLs 				  []URL 	`json:"urls,omitempty"`
+		Secrets 			  []Secret 	`json:"secrets,omitempty"`
 
 	}{
 		Provider 			: file.Provider,
@@ -185,9 +265,38 @@ func (file File) MarshalJSON() ([]byte, error) {
 		MIMEType 			: file.MIMEType,
 		Fingerprint			: file.Fingerprint,
 		Content			 	: file.Content,
+		MailFrom		 	: file.MailFrom,
+		MailSubject		 	: file.MailSubject,
+		Credentials		 	: file.Credentials,
+		Emails			 	: file.Emails,
+		URLs			 	: file.URLs,
+		Secrets			 	: file.Secrets,
 	})
 }
 
+/* Custom Unmarshaller for File (e.g. JSON lines read back by the reports) */
+func (file *File) UnmarshalJSON(data []byte) error {
+	type fileAlias File
+	aux := &struct {
+		LeakDate              string    `json:"leak_date"`
+		IndexedAt             string    `json:"indexed_at"`
+		*fileAlias
+	}{
+		fileAlias 			: (*fileAlias)(file),
+	}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+
+	if dt, err := time.Parse(time.RFC3339, aux.LeakDate); err == nil {
+		file.Date = dt
+	}
+	if dt, err := time.Parse(time.RFC3339, aux.IndexedAt); err == nil {
+		file.IndexedAt = dt
+	}
+	return nil
+}
+
 
 /* Custom Marshaller for Credential */
 func (cred Credential) MarshalJSON() ([]byte, error) {
@@ -195,43 +304,111 @@ func (cred Credential) MarshalJSON() ([]byte, error) {
 		Rule                  string    `json:"rule"`
 		Time 	              string    `json:"time"`
 		UserDomain 	    	  string   	`json:"user_domain,omitempty"`
+		UserRegistrableDomain string   	`json:"user_registrable_domain,omitempty"`
 		Username    		  string    `json:"username"`
 		Password	    	  string   	`json:"password"`
 		CPF         		  string    `json:"cpf,omitempty"`
 		Url 		    	  string   	`json:"url,omitempty"`
 		UrlDomain			  string    `json:"url_domain,omitempty"`
+		UrlRegistrableDomain  string    `json:"url_registrable_domain,omitempty"`
+		Client                string    `json:"client,omitempty"`
 		Severity	    	  int   	`json:"severity"`
 		Entropy  	    	  float32  	`json:"entropy"`
+		StartLine 	    	  int   	`json:"start_line"`
+		EndLine 	    	  int   	`json:"end_line"`
+		StartColumn 	      int   	`json:"start_column"`
+		EndColumn 	    	  int   	`json:"end_column"`
+		Offset 	    		  int   	`json:"offset"`
+		Location              string    `json:"location,omitempty"`
 		NearText	    	  string   	`json:"near_text"`
 
 	}{
 		Rule 				: cred.Rule,
 		Time 	    		: cred.Time.Format(time.RFC3339),
 		UserDomain			: strings.ToLower(cred.UserDomain),
+		UserRegistrableDomain : cred.UserRegistrableDomain,
 		Username 			: cred.Username,
 		Password 			: cred.Password,
 		CPF 				: cred.CPF,
 		Url 				: cred.Url,
 		UrlDomain			: strings.ToLower(cred.UrlDomain),
+		UrlRegistrableDomain : cred.UrlRegistrableDomain,
+		Client 				: cred.Client,
 		Severity 			: cred.Severity,
 		Entropy 			: cred.Entropy,
+		StartLine 			: cred.StartLine,
+		EndLine 			: cred.EndLine,
+		StartColumn 		: cred.StartColumn,
+		EndColumn 			: cred.EndColumn,
+		Offset 				: cred.Offset,
+		Location 			: cred.Location,
 		NearText 			: cred.NearText,
 	})
 }
 
 
+/* Custom Marshaller for Secret */
+func (sec Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Rule                  string    `json:"rule"`
+		Time 	              string    `json:"time"`
+		Type 		    	  string   	`json:"type"`
+		Value 		    	  string   	`json:"value"`
+		Entropy  	    	  float32  	`json:"entropy"`
+		Client                string    `json:"client,omitempty"`
+		StartLine 	    	  int   	`json:"start_line"`
+		EndLine 	    	  int   	`json:"end_line"`
+		StartColumn 	      int   	`json:"start_column"`
+		EndColumn 	    	  int   	`json:"end_column"`
+		Offset 	    		  int   	`json:"offset"`
+		Location              string    `json:"location,omitempty"`
+		NearText	    	  string   	`json:"near_text"`
+
+	}{
+		Rule 				: sec.Rule,
+		Time 	    		: sec.Time.Format(time.RFC3339),
+		Type 				: sec.Type,
+		Value 				: sec.Value,
+		Entropy 			: sec.Entropy,
+		Client 				: sec.Client,
+		StartLine 			: sec.StartLine,
+		EndLine 			: sec.EndLine,
+		StartColumn 		: sec.StartColumn,
+		EndColumn 			: sec.EndColumn,
+		Offset 				: sec.Offset,
+		Location 			: sec.Location,
+		NearText 			: sec.NearText,
+	})
+}
+
 /* Custom Marshaller for URL */
 func (u URL) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Time 	              string    `json:"time"`
 		Domain   	    	  string   	`json:"domain"`
+		RegistrableDomain     string   	`json:"registrable_domain,omitempty"`
 		Url 		    	  string   	`json:"url"`
+		Client                string    `json:"client,omitempty"`
+		StartLine 	    	  int   	`json:"start_line"`
+		EndLine 	    	  int   	`json:"end_line"`
+		StartColumn 	      int   	`json:"start_column"`
+		EndColumn 	    	  int   	`json:"end_column"`
+		Offset 	    		  int   	`json:"offset"`
+		Location              string    `json:"location,omitempty"`
 		NearText	    	  string   	`json:"near_text"`
 
 	}{
 		Time 	    		: u.Time.Format(time.RFC3339),
 		Domain 				: strings.ToLower(u.Domain),
+		RegistrableDomain 	: u.RegistrableDomain,
 		Url 				: u.Url,
+		Client 				: u.Client,
+		StartLine 			: u.StartLine,
+		EndLine 			: u.EndLine,
+		StartColumn 		: u.StartColumn,
+		EndColumn 			: u.EndColumn,
+		Offset 				: u.Offset,
+		Location 			: u.Location,
 		NearText 			: u.NearText,
 	})
 }
@@ -241,13 +418,29 @@ func (eml Email) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Time 	              string    `json:"time"`
 		Domain   	    	  string   	`json:"domain"`
+		RegistrableDomain     string   	`json:"registrable_domain,omitempty"`
 		Email 		    	  string   	`json:"email"`
+		Client                string    `json:"client,omitempty"`
+		StartLine 	    	  int   	`json:"start_line"`
+		EndLine 	    	  int   	`json:"end_line"`
+		StartColumn 	      int   	`json:"start_column"`
+		EndColumn 	    	  int   	`json:"end_column"`
+		Offset 	    		  int   	`json:"offset"`
+		Location              string    `json:"location,omitempty"`
 		NearText	    	  string   	`json:"near_text"`
 
 	}{
 		Time 	    		: eml.Time.Format(time.RFC3339),
 		Domain 				: strings.ToLower(eml.Domain),
+		RegistrableDomain 	: eml.RegistrableDomain,
 		Email 				: strings.ToLower(eml.Email),
+		Client 				: eml.Client,
+		StartLine 			: eml.StartLine,
+		EndLine 			: eml.EndLine,
+		StartColumn 		: eml.StartColumn,
+		EndColumn 			: eml.EndColumn,
+		Offset 				: eml.Offset,
+		Location 			: eml.Location,
 		NearText 			: eml.NearText,
 	})
 }
@@ -271,6 +464,12 @@ func (eml Email) CalcHash(additional_data string) string {
 	return hash
 }
 
+func (sec Secret) CalcHash(additional_data string) string {
+	var hash string
+	_calcHash(&hash, additional_data, sec.Time, sec.Rule, sec.Value)
+	return hash
+}
+
 func _calcHash(outValue *string, keyvals ...interface{}) {
 
 	data := ""
@@ -303,6 +502,8 @@ func (file *File) Sanitize() {
 	file.MIMEType = tools.SanitizeUTF8(file.MIMEType)
 	file.Fingerprint = tools.SanitizeUTF8(file.Fingerprint)
 	file.Content = tools.SanitizeUTF8(file.Content)
+	file.MailFrom = tools.SanitizeUTF8(file.MailFrom)
+	file.MailSubject = tools.SanitizeUTF8(file.MailSubject)
 	file.FailedReason = tools.SanitizeUTF8(file.FailedReason)
 
 	for i := range file.Credentials {
@@ -314,30 +515,53 @@ func (file *File) Sanitize() {
 	for i := range file.URLs {
 		file.URLs[i].Sanitize()
 	}
+	for i := range file.Secrets {
+		file.Secrets[i].Sanitize()
+	}
 }
 
 // Sanitize removes null bytes and invalid UTF-8 sequences from all string fields
 func (cred *Credential) Sanitize() {
 	cred.Rule = tools.SanitizeUTF8(cred.Rule)
 	cred.UserDomain = tools.SanitizeUTF8(cred.UserDomain)
+	cred.UserRegistrableDomain = tools.SanitizeUTF8(cred.UserRegistrableDomain)
 	cred.Username = tools.SanitizeUTF8(cred.Username)
 	cred.Password = tools.SanitizeUTF8(cred.Password)
 	cred.CPF = tools.SanitizeUTF8(cred.CPF)
 	cred.Url = tools.SanitizeUTF8(cred.Url)
 	cred.UrlDomain = tools.SanitizeUTF8(cred.UrlDomain)
+	cred.UrlRegistrableDomain = tools.SanitizeUTF8(cred.UrlRegistrableDomain)
+	cred.Client = tools.SanitizeUTF8(cred.Client)
+	cred.Location = tools.SanitizeUTF8(cred.Location)
 	cred.NearText = tools.SanitizeUTF8(cred.NearText)
 }
 
 // Sanitize removes null bytes and invalid UTF-8 sequences from all string fields
 func (eml *Email) Sanitize() {
 	eml.Domain = tools.SanitizeUTF8(eml.Domain)
+	eml.RegistrableDomain = tools.SanitizeUTF8(eml.RegistrableDomain)
 	eml.Email = tools.SanitizeUTF8(eml.Email)
+	eml.Client = tools.SanitizeUTF8(eml.Client)
+	eml.Location = tools.SanitizeUTF8(eml.Location)
 	eml.NearText = tools.SanitizeUTF8(eml.NearText)
 }
 
 // Sanitize removes null bytes and invalid UTF-8 sequences from all string fields
 func (u *URL) Sanitize() {
 	u.Domain = tools.SanitizeUTF8(u.Domain)
+	u.RegistrableDomain = tools.SanitizeUTF8(u.RegistrableDomain)
 	u.Url = tools.SanitizeUTF8(u.Url)
+	u.Client = tools.SanitizeUTF8(u.Client)
+	u.Location = tools.SanitizeUTF8(u.Location)
 	u.NearText = tools.SanitizeUTF8(u.NearText)
-}
\ No newline at end of file
+}
+
+// Sanitize removes null bytes and invalid UTF-8 sequences from all string fields
+func (sec *Secret) Sanitize() {
+	sec.Rule = tools.SanitizeUTF8(sec.Rule)
+	sec.Type = tools.SanitizeUTF8(sec.Type)
+	sec.Value = tools.SanitizeUTF8(sec.Value)
+	sec.Location = tools.SanitizeUTF8(sec.Location)
+	sec.Client = tools.SanitizeUTF8(sec.Client)
+	sec.NearText = tools.SanitizeUTF8(sec.NearText)
+}
@@ -34,4 +34,26 @@ func ReadFileList(fileName string, outList *[]string) error {
 	}
 
 	return scanner.Err()
-}
\ No newline at end of file
+}
+// ReadFileLines reads the non empty lines of a file keeping their case
+// (e.g. passwords)
+func ReadFileLines(fileName string, outList *[]string) error {
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate := strings.TrimRight(scanner.Text(), "\r")
+		if candidate == "" {
+			continue
+		}
+
+		*outList = append(*outList, candidate)
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/helviojunior/intelparser/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ClientsFile is the client mapping file (TOML or YAML)
+//
+//	[[clients]]
+//	name = "acme"
+//	domains = ["acme.com", "acme.com.br"]
+//	emails = ["ceo.acme@gmail.com", "*@acme-partner.com"]
+//	keywords = ["acmecorp"]
+type ClientsFile struct {
+	Clients []ClientConfig `toml:"clients" yaml:"clients"`
+}
+
+// ClientConfig is one client (or case) of the mapping file
+type ClientConfig struct {
+	Name     string   `toml:"name" yaml:"name"`
+	Domains  []string `toml:"domains" yaml:"domains"`
+	Emails   []string `toml:"emails" yaml:"emails"`
+	Keywords []string `toml:"keywords" yaml:"keywords"`
+}
+
+// Clients tags the findings with the client they belong to
+type Clients struct {
+	names      []string
+	watchlists []*Watchlist
+	patterns   [][]*regexp.Regexp
+}
+
+// LoadClientsFile reads a TOML (default) or YAML (.yaml/.yml) client
+// mapping file
+func LoadClientsFile(file_path string) (*Clients, error) {
+	data, err := os.ReadFile(file_path)
+	if err != nil {
+		return nil, err
+	}
+
+	cf := &ClientsFile{}
+	switch strings.ToLower(filepath.Ext(file_path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cf)
+	default:
+		err = toml.Unmarshal(data, cf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(file_path), err)
+	}
+
+	return NewClients(cf.Clients)
+}
+
+// NewClients compiles the clients. The domains match themselves and their
+// subdomains, the e-mails the whole address (or the pattern, with *
+// wildcards) and the keywords anywhere.
+func NewClients(configs []ClientConfig) (*Clients, error) {
+	c := &Clients{}
+	for _, cc := range configs {
+		name := strings.TrimSpace(cc.Name)
+		if name == "" {
+			return nil, fmt.Errorf("client without name")
+		}
+
+		w := &Watchlist{seen: map[string]bool{}}
+		patterns := []*regexp.Regexp{}
+		for _, d := range cc.Domains {
+			w.add(d, watchDomain)
+		}
+		for _, e := range cc.Emails {
+			if !strings.Contains(e, "*") {
+				w.add(e, watchEmail)
+				continue
+			}
+			re, err := regexp.Compile("(?i)^" + strings.ReplaceAll(regexp.QuoteMeta(strings.TrimSpace(e)), `\*`, `[^\s@]*`) + "$")
+			if err != nil {
+				return nil, fmt.Errorf("client %s: invalid e-mail pattern %q: %w", name, e, err)
+			}
+			patterns = append(patterns, re)
+		}
+		for _, k := range cc.Keywords {
+			w.add(k, watchKeyword)
+		}
+
+		c.names = append(c.names, name)
+		c.watchlists = append(c.watchlists, w.build())
+		c.patterns = append(c.patterns, patterns)
+	}
+	return c, nil
+}
+
+// Len returns the number of clients
+func (c *Clients) Len() int {
+	return len(c.names)
+}
+
+// Tag returns the first client (in the file order) of the e-mail or
+// username and the other values, or an empty string
+func (c *Clients) Tag(user string, values ...string) string {
+	user = strings.TrimSpace(user)
+	for i, name := range c.names {
+		for _, re := range c.patterns[i] {
+			if user != "" && re.MatchString(user) {
+				return name
+			}
+		}
+		if c.watchlists[i].Match(append([]string{user}, values...)...) {
+			return name
+		}
+	}
+	return ""
+}
+
+// TagCredential returns the client of the credential
+func (c *Clients) TagCredential(cred models.Credential) string {
+	return c.Tag(cred.Username, cred.UserDomain, cred.Url, cred.UrlDomain)
+}
+
+// TagEmail returns the client of the e-mail
+func (c *Clients) TagEmail(e models.Email) string {
+	return c.Tag(e.Email)
+}
+
+// TagURL returns the client of the URL
+func (c *Clients) TagURL(u models.URL) string {
+	return c.Tag("", u.Url)
+}
+
+// TagSecret returns the client of the secret (e.g. the host of a
+// connection string)
+func (c *Clients) TagSecret(s models.Secret) string {
+	return c.Tag("", s.Value)
+}
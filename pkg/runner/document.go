@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/helviojunior/intelparser/pkg/extract"
+	"github.com/helviojunior/intelparser/pkg/models"
+	"github.com/helviojunior/intelparser/pkg/runner/rules"
+)
+
+// detectDocument extracts the text of the OOXML or PDF document and runs
+// the rules over it, one section (sheet, page or slide) at a time. The line
+// numbers and offsets of the findings are relative to their section, named
+// by the finding Location. The rules of pathAllowed are skipped and the
+// findings are passed to add.
+func (run *Runner) detectDocument(file *models.File, item FileItem, format extract.Format, pathAllowed map[*rules.Rule]bool, add func(*models.File, models.Finding)) error {
+	logger := run.log.With("path", file.FilePath, "format", format)
+	logger.Debug("Extracting document text")
+
+	r, size, closer, err := documentReader(item)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	return extract.Walk(format, r, size, func(section extract.Section) error {
+		totalLines := 0
+		chunkOffset := 0
+		for _, chunk := range documentChunks(section.Text) {
+			if !run.status.Running {
+				return nil
+			}
+
+			fragment := Fragment{
+				Raw:         chunk,
+				Bytes:       []byte(chunk),
+				FilePath:    file.FilePath,
+				pathAllowed: pathAllowed,
+			}
+			for _, finding := range run.Detect(fragment) {
+				// need to add 1 since line counting starts at 1
+				finding.StartLine += totalLines + 1
+				finding.EndLine += totalLines + 1
+				finding.Offset += chunkOffset
+				finding.Location = section.Name
+				add(file, finding)
+			}
+
+			totalLines += strings.Count(chunk, "\n")
+			chunkOffset += len(chunk)
+		}
+		return nil
+	})
+}
+
+// documentReader returns a random access reader of the item: the file on
+// disk or, for streamed items, its content read into memory
+func documentReader(item FileItem) (io.ReaderAt, int64, func(), error) {
+	if !item.IsStream() && item.RealPath != "" {
+		f, err := os.Open(item.RealPath)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		st, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, nil, err
+		}
+		return f, st.Size(), func() { _ = f.Close() }, nil
+	}
+
+	rc, err := item.Open()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return bytes.NewReader(data), int64(len(data)), func() {}, nil
+}
+
+// documentChunks splits the text in chunks of about chunkSize, at line
+// boundaries
+func documentChunks(text string) []string {
+	chunks := []string{}
+	for len(text) > chunkSize {
+		cut := strings.LastIndexByte(text[:chunkSize], '\n')
+		if cut < 0 {
+			cut = chunkSize - 1
+		}
+		chunks = append(chunks, text[:cut+1])
+		text = text[cut+1:]
+	}
+	if strings.TrimSpace(text) != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
@@ -1,5 +1,8 @@
 package runner
 
+import (
+    "github.com/helviojunior/intelparser/pkg/runner/rules"
+)
 
 // Fragment contains the data to be scanned
 type Fragment struct {
@@ -18,4 +21,8 @@ type Fragment struct {
     // newlineIndices is a list of indices of newlines in the raw content.
     // This is used to calculate the line location of a finding
     newlineIndices [][]int
+
+    // pathAllowed has the rules whose allowlists match FilePath. They are
+    // not run over the fragment (see pathAllowlisted).
+    pathAllowed map[*rules.Rule]bool
 }
\ No newline at end of file
@@ -48,12 +48,17 @@ type Writer struct {
     DbDebug   bool // enables verbose database logs
     Csv       bool
     CsvFile   string
+    CsvMode   string // files (one row per file) or entities (one CSV per entity)
     Jsonl     bool
     JsonlFile string
+    Xlsx      bool
+    XlsxFile  string
     ELastic   bool
     ELasticURI string
     ELasticDebug bool // promotes ElasticSearch writer operational logs from Debug to Info
     Stdout    bool
+    StdoutFormat string // jsonl, tsv or table
+    StdoutEntities []string // creds, emails, urls and secrets
     None      bool
 }
 
@@ -68,6 +73,23 @@ type Parser struct {
     NearTextSize int
 
     StoreNearText bool
+
+    // Declarative rules file (TOML or YAML)
+    RulesFile string
+    // Exact values (one per line) to be ignored by all rules
+    AllowlistFile string
+    // Domains, e-mails and keywords (one per line) of the findings to keep
+    WatchlistFile string
+    // Client mapping file (TOML or YAML), to tag the findings
+    ClientsFile string
+    // Column synonyms file (TOML or YAML) of the CSV, TSV and JSON files
+    ColumnsFile string
+    // How many levels of nested archives are expanded
+    ArchiveDepth int
+    // Candidate passwords of encrypted ZIP archives
+    ArchivePasswords []string
+    // File with candidate passwords (one per line)
+    ArchivePasswordFile string
 }
 
 // NewDefaultOptions returns Options with some default values
@@ -4,14 +4,145 @@ import (
 	"fmt"
 	//"bufio"
 	//"bytes"
-	//"io"
+	"io"
+	"os"
+	"path/filepath"
 
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/archive"
+	"github.com/helviojunior/intelparser/pkg/mailbox"
 	"github.com/helviojunior/intelparser/pkg/models"
 )
 
 type FileItem struct {
 	RealPath     string
 	VirtualPath  string
+
+	// Opener streams the file content (e.g. a ZIP entry) when the file is
+	// not on disk. It may be called more than once.
+	Opener       func() (io.ReadCloser, error)
+	// StreamSize is the (uncompressed) size of the streamed content
+	StreamSize   int64
+
+	// Reader is a single pass content (e.g. stdin), with no size known
+	// in advance
+	Reader       io.Reader
+
+	// Release is called by the runner once the file was parsed
+	Release      func()
+
+	// Mail is the origin of the message text or attachment, when the file
+	// came from a mailbox
+	Mail         *mailbox.Header
+}
+
+// IsStream returns true if the content is not read from RealPath
+func (f FileItem) IsStream() bool {
+	return f.Opener != nil || f.Reader != nil
+}
+
+// IsReader returns true if the content can be read only once
+func (f FileItem) IsReader() bool {
+	return f.Reader != nil
+}
+
+// Path returns the disk path or, for streamed files, the virtual path
+func (f FileItem) Path() string {
+	if f.IsStream() || f.RealPath == "" {
+		return f.VirtualPath
+	}
+	return f.RealPath
+}
+
+// Name returns the file name
+func (f FileItem) Name() string {
+	return filepath.Base(f.Path())
+}
+
+// Open returns a reader of the file content
+func (f FileItem) Open() (io.ReadCloser, error) {
+	if f.IsReader() {
+		return io.NopCloser(f.Reader), nil
+	}
+	if f.IsStream() {
+		return f.Opener()
+	}
+	return os.Open(f.RealPath)
+}
+
+// Size returns the file content size
+func (f FileItem) Size() (int64, error) {
+	if f.IsStream() {
+		return f.StreamSize, nil
+	}
+	st, err := os.Stat(f.RealPath)
+	if err != nil {
+		return 0, err
+	}
+	return st.Size(), nil
+}
+
+// Fingerprint returns the SHA1 hash of the file content
+func (f FileItem) Fingerprint() (string, error) {
+	r, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	return tools.GetHashFromReader(r)
+}
+
+// MimeType sniffs the mime type of the file content
+func (f FileItem) MimeType() (string, error) {
+	r, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	return tools.GetMimeTypeFromReader(r)
+}
+
+// ReadText reads all the file content as text
+func (f FileItem) ReadText() (string, error) {
+	r, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	return tools.ReadText(r)
+}
+
+// SetMail records the From, Subject and Date of the message the file came
+// from, if any
+func (f FileItem) SetMail(file *models.File) {
+	if f.Mail == nil {
+		return
+	}
+	file.MailFrom = f.Mail.From
+	file.MailSubject = f.Mail.Subject
+	if !f.Mail.Date.IsZero() {
+		file.Date = f.Mail.Date
+	}
+}
+
+// ArchiveFileItem returns a FileItem streamed from the archive member
+func ArchiveFileItem(e archive.Entry) FileItem {
+	return FileItem{
+		VirtualPath: e.VirtualPath,
+		Opener:      e.Opener,
+		StreamSize:  e.Size,
+		Release:     e.Release,
+	}
+}
+
+// Done releases the resources backing the file (if any)
+func (f FileItem) Done() {
+	if f.Release != nil {
+		f.Release()
+	}
 }
 
 // ChromeNotFoundError signals that chrome is not available
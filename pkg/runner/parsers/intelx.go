@@ -10,6 +10,7 @@ import (
 	"bufio"
 	"log/slog"
 	"encoding/csv"
+	"io"
 	"os"
 	//"os/exec"
 	//"path/filepath"
@@ -87,14 +88,14 @@ func NewInteX(logger *slog.Logger, opts runner.Options) (*IntelxParser, error) {
 // witness does the work of probing a url.
 // This is where everything comes together as far as the runner is concerned.
 func (run *IntelxParser) ParseFile(thisRunner *runner.Runner, file runner.FileItem) (*models.File, error) {
-	logger := run.log.With("file_path", file.RealPath)
+	logger := run.log.With("file_path", file.Path())
 	var err error
 
-	file_name_ext := filepath.Base(file.RealPath)
+	file_name_ext := file.Name()
 	var (
 		result = &models.File{
 			Provider: "IntelX",
-			FilePath: file.RealPath,
+			FilePath: file.Path(),
 			FileName: file_name_ext,
 			Name: file.VirtualPath,
 			Date: time.Now(),
@@ -103,13 +104,18 @@ func (run *IntelxParser) ParseFile(thisRunner *runner.Runner, file runner.FileIt
 	)
 
 	if strings.ToLower(file_name_ext) == "info.csv" {
-		err = run.ParseInfo(file.RealPath)
+		var r io.ReadCloser
+		if r, err = file.Open(); err != nil {
+			return result, err
+		}
+		defer r.Close()
+		err = run.ParseInfoReader(r)
 		return result, err
 	}
 
 	file_name := strings.ToLower(strings.TrimSuffix(file_name_ext, filepath.Ext(file_name_ext)))
-	result.Fingerprint, _ = tools.GetHashFromFile(file.RealPath)
-	result.MIMEType, _ = tools.GetMimeType(file.RealPath)
+	result.Fingerprint, _ = file.Fingerprint()
+	result.MIMEType, _ = file.MimeType()
 
 	if run.conn != nil {
 		response := run.conn.Raw("SELECT count(id) as count from files WHERE failed = false AND file_name = ? AND fingerprint = ?", file_name_ext, result.Fingerprint)
@@ -123,7 +129,7 @@ func (run *IntelxParser) ParseFile(thisRunner *runner.Runner, file runner.FileIt
 	    }
 	}
 
-	idx := slices.IndexFunc(run.info, func(i InfoData) bool { return i.SystemID == file_name })
+	idx := run.getInfoIndex(file_name, file.VirtualPath)
 	logger.Debug("Get info", "info_idx", idx)
 
 	if idx == -1 {
@@ -153,17 +159,20 @@ func (run *IntelxParser) ParseFile(thisRunner *runner.Runner, file runner.FileIt
 		result.ProviderId = file_name
 	}
 
+	// Mailbox parts: the message origin
+	file.SetMail(result)
+
 	logger = run.log.With("file", file_name_ext)
 	logger.Debug("Parsing file")
 
-	if err := thisRunner.DetectFile(result); err != nil {
+	if err := thisRunner.DetectFileItem(result, file); err != nil {
 		return result, err
 	}
 
 	//Check if we must save the file content
 	if run.MustSaveContent(result) { //&& result.MIMEType == "text/plain" {
 		logger.Debug("saving file content")
-		result.Content, _ = tools.ReadTextFile(result.FilePath)
+		result.Content, _ = file.ReadText()
 	}
 
 	result.FilePath = file.VirtualPath
@@ -171,6 +180,30 @@ func (run *IntelxParser) ParseFile(thisRunner *runner.Runner, file runner.FileIt
 	return result, nil
 }
 
+// getInfoIndex looks for the file at the Info.csv data. Files extracted
+// from nested archives (e.g. <system id>.tar.gz/file.txt) get the info of
+// the archive they came from.
+func (run *IntelxParser) getInfoIndex(file_name string, virtual_path string) int {
+	idx := slices.IndexFunc(run.info, func(i InfoData) bool { return i.SystemID == file_name })
+	if idx >= 0 {
+		return idx
+	}
+
+	parts := strings.Split(virtual_path, "/")
+	for i := len(parts) - 2; i >= 0; i-- {
+		name := strings.ToLower(parts[i])
+		for n := 0; n < 2 && filepath.Ext(name) != ""; n++ {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+			idx = slices.IndexFunc(run.info, func(i InfoData) bool { return i.SystemID == name })
+			if idx >= 0 {
+				return idx
+			}
+		}
+	}
+
+	return -1
+}
+
 func (run *IntelxParser) MustSaveContent(file *models.File) bool {
     s := strings.ToLower(file.Bucket)
     n := strings.ToLower(file.Name)
@@ -207,6 +240,11 @@ func (run *IntelxParser) ParseInfo(file_path string) error {
     }
     defer f.Close()
 
+    return run.ParseInfoReader(f)
+}
+
+// ParseInfoReader parses the Info.csv content of r
+func (run *IntelxParser) ParseInfoReader(f io.Reader) error {
     br := bufio.NewReader(f)
     r, _, err := br.ReadRune()
     if err != nil {
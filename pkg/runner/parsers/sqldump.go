@@ -0,0 +1,838 @@
+package driver
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/models"
+	"github.com/helviojunior/intelparser/pkg/runner"
+)
+
+// SqlDumpExt is the extension of the database dumps (MySQL and PostgreSQL
+// plain-text dumps)
+const SqlDumpExt = ".sql"
+
+// IsSqlDump tells if the file is a database dump, by its name
+func IsSqlDump(file_name string) bool {
+	return strings.EqualFold(filepath.Ext(file_name), SqlDumpExt)
+}
+
+// SQL token kinds
+const (
+	sqlEOF = iota
+	sqlWord
+	sqlIdent
+	sqlString
+	sqlPunct
+)
+
+// sqlToken is one token of the dump: a bare word (keyword, number or
+// name), a quoted identifier, a string literal or a punctuation
+type sqlToken struct {
+	kind int
+	text string
+	line int
+}
+
+func (t sqlToken) is(kind int, text string) bool {
+	return t.kind == kind && strings.EqualFold(t.text, text)
+}
+
+// sqlLexer splits the dump into tokens, one byte at a time, so the INSERT
+// statements (often megabytes long) are never held in memory
+type sqlLexer struct {
+	r    *bufio.Reader
+	line int
+	err  error
+	// MySQL strings (and PostgreSQL ones, unless standard_conforming_strings
+	// is on) have backslash escapes
+	backslash bool
+}
+
+func newSqlLexer(r io.Reader) *sqlLexer {
+	return &sqlLexer{
+		r:         bufio.NewReaderSize(r, 64*1024),
+		line:      1,
+		backslash: true,
+	}
+}
+
+func (lx *sqlLexer) readByte() (byte, bool) {
+	c, err := lx.r.ReadByte()
+	if err != nil {
+		if err != io.EOF {
+			lx.err = err
+		}
+		return 0, false
+	}
+	if c == '\n' {
+		lx.line++
+	}
+	return c, true
+}
+
+func (lx *sqlLexer) unreadByte(c byte) {
+	_ = lx.r.UnreadByte()
+	if c == '\n' {
+		lx.line--
+	}
+}
+
+func (lx *sqlLexer) peekByte() byte {
+	if b, err := lx.r.Peek(1); err == nil {
+		return b[0]
+	}
+	return 0
+}
+
+// readLine returns the rest of the current line, without the line break
+func (lx *sqlLexer) readLine() (string, bool) {
+	s, err := lx.r.ReadString('\n')
+	if err != nil && s == "" {
+		if err != io.EOF {
+			lx.err = err
+		}
+		return "", false
+	}
+	if strings.HasSuffix(s, "\n") {
+		lx.line++
+	}
+	return strings.TrimRight(s, "\r\n"), true
+}
+
+func (lx *sqlLexer) skipComment() {
+	prev := byte(0)
+	for {
+		c, ok := lx.readByte()
+		if !ok || (prev == '*' && c == '/') {
+			return
+		}
+		prev = c
+	}
+}
+
+// readQuoted reads a string literal or a quoted identifier up to the
+// closing quote. A doubled quote is the quote itself.
+func (lx *sqlLexer) readQuoted(quote byte, backslash bool) string {
+	var b strings.Builder
+	for {
+		c, ok := lx.readByte()
+		if !ok {
+			return b.String()
+		}
+		if backslash && c == '\\' {
+			if c, ok = lx.readByte(); !ok {
+				return b.String()
+			}
+			b.WriteByte(sqlUnescape(c))
+			continue
+		}
+		if c == quote {
+			if lx.peekByte() != quote {
+				return b.String()
+			}
+			lx.readByte()
+		}
+		b.WriteByte(c)
+	}
+}
+
+// readDollarQuoted reads a PostgreSQL dollar-quoted string ($$...$$ or
+// $tag$...$tag$), if there is one after the first $
+func (lx *sqlLexer) readDollarQuoted() (string, bool) {
+	tag := "$"
+	for i := 1; i < 64; i++ {
+		p, err := lx.r.Peek(i)
+		if err != nil {
+			return "", false
+		}
+		c := p[i-1]
+		if c == '$' {
+			tag += string(p)
+			_, _ = lx.r.Discard(i)
+			break
+		}
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return "", false
+		}
+	}
+	if len(tag) < 2 {
+		return "", false
+	}
+
+	var b strings.Builder
+	for {
+		c, ok := lx.readByte()
+		if !ok {
+			return b.String(), true
+		}
+		b.WriteByte(c)
+		if c == '$' && strings.HasSuffix(b.String(), tag) {
+			return strings.TrimSuffix(b.String(), tag), true
+		}
+	}
+}
+
+// next returns the next token, skipping the blanks and the comments
+func (lx *sqlLexer) next() sqlToken {
+	for {
+		c, ok := lx.readByte()
+		if !ok {
+			return sqlToken{kind: sqlEOF, line: lx.line}
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case c == '#' || (c == '-' && lx.peekByte() == '-'):
+			_, _ = lx.readLine()
+			continue
+		case c == '/' && lx.peekByte() == '*':
+			lx.skipComment()
+			continue
+		}
+
+		line := lx.line
+		switch c {
+		case '(', ')', ',', ';':
+			return sqlToken{kind: sqlPunct, text: string(c), line: line}
+		case '\'':
+			return sqlToken{kind: sqlString, text: lx.readQuoted(c, lx.backslash), line: line}
+		case '`', '"':
+			return sqlToken{kind: sqlIdent, text: lx.readQuoted(c, false), line: line}
+		case '$':
+			if s, ok := lx.readDollarQuoted(); ok {
+				return sqlToken{kind: sqlString, text: s, line: line}
+			}
+		}
+
+		var b strings.Builder
+		b.WriteByte(c)
+		for {
+			c, ok = lx.readByte()
+			if !ok {
+				break
+			}
+			if strings.IndexByte(" \t\r\n(),;'`\"", c) >= 0 {
+				lx.unreadByte(c)
+				break
+			}
+			b.WriteByte(c)
+		}
+		return sqlToken{kind: sqlWord, text: b.String(), line: line}
+	}
+}
+
+func sqlUnescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case '0':
+		return 0
+	case 'Z':
+		return 0x1a
+	}
+	return c
+}
+
+// sqlTableFunc returns the row handler of the table (by its columns), or
+// nil to ignore its rows
+type sqlTableFunc func(table string, columns []string) func(values []string, line int)
+
+// Keywords between the statement and the table name
+var sqlNameKeywords = map[string]bool{
+	"IF": true, "NOT": true, "EXISTS": true, "ONLY": true, "INTO": true,
+	"IGNORE": true, "LOW_PRIORITY": true, "DELAYED": true, "HIGH_PRIORITY": true,
+	"TABLE": true, "TEMPORARY": true, "TEMP": true, "UNLOGGED": true,
+}
+
+// Keywords after the table name
+var sqlStopKeywords = map[string]bool{
+	"VALUES": true, "VALUE": true, "SELECT": true, "SET": true, "DEFAULT": true,
+	"FROM": true, "TO": true, "AS": true, "LIKE": true, "WITH": true,
+}
+
+// Table elements of CREATE TABLE that are not columns
+var sqlConstraintKeywords = map[string]bool{
+	"PRIMARY": true, "KEY": true, "UNIQUE": true, "CONSTRAINT": true,
+	"INDEX": true, "FOREIGN": true, "CHECK": true, "FULLTEXT": true,
+	"SPATIAL": true, "EXCLUDE": true, "LIKE": true, "PERIOD": true,
+}
+
+// sqlDump reads the table definitions and the rows of a dump
+type sqlDump struct {
+	lx     *sqlLexer
+	tables map[string][]string
+	table  sqlTableFunc
+}
+
+// readSqlDump streams the rows of the INSERT statements and of the COPY
+// blocks of the dump. The rows of INSERT statements with no column list
+// are mapped to the columns of the CREATE TABLE statement.
+func readSqlDump(r io.Reader, table sqlTableFunc) error {
+	d := &sqlDump{
+		lx:     newSqlLexer(r),
+		tables: map[string][]string{},
+		table:  table,
+	}
+
+	for {
+		tok := d.lx.next()
+		switch {
+		case tok.kind == sqlEOF:
+			return d.lx.err
+		case tok.is(sqlPunct, ";"):
+			continue
+		case tok.kind == sqlWord && strings.HasPrefix(tok.text, "\\"):
+			// psql meta-commands (\connect, \restrict) end at the line break
+			_, _ = d.lx.readLine()
+		case tok.kind != sqlWord:
+			d.skipStatement()
+		default:
+			switch strings.ToUpper(tok.text) {
+			case "CREATE":
+				d.parseCreate()
+			case "INSERT", "REPLACE":
+				d.parseInsert()
+			case "COPY":
+				d.parseCopy()
+			case "SET":
+				d.parseSet()
+			default:
+				d.skipStatement()
+			}
+		}
+	}
+}
+
+// skipStatement consumes the tokens up to the end of the statement
+func (d *sqlDump) skipStatement() {
+	for {
+		tok := d.lx.next()
+		if tok.kind == sqlEOF || tok.is(sqlPunct, ";") {
+			return
+		}
+	}
+}
+
+// skipAfter skips the rest of the statement, unless tok already ended it
+func (d *sqlDump) skipAfter(tok sqlToken) {
+	if tok.kind != sqlEOF && !tok.is(sqlPunct, ";") {
+		d.skipStatement()
+	}
+}
+
+// tableName reads the table name (with no schema or database) and returns
+// the token after it
+func (d *sqlDump) tableName() (string, sqlToken) {
+	var b strings.Builder
+	for {
+		tok := d.lx.next()
+		if tok.kind == sqlEOF || tok.kind == sqlPunct || tok.kind == sqlString {
+			return sqlTableName(b.String()), tok
+		}
+		if tok.kind == sqlWord {
+			kw := strings.ToUpper(tok.text)
+			if sqlStopKeywords[kw] {
+				return sqlTableName(b.String()), tok
+			}
+			if sqlNameKeywords[kw] {
+				b.Reset()
+				continue
+			}
+		}
+		b.WriteString(tok.text)
+	}
+}
+
+func sqlTableName(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(strings.Trim(name, "`\" "))
+}
+
+// readColumns reads a column list, after its opening parenthesis
+func (d *sqlDump) readColumns() []string {
+	cols := []string{}
+	for {
+		tok := d.lx.next()
+		switch {
+		case tok.kind == sqlEOF, tok.is(sqlPunct, ")"), tok.is(sqlPunct, ";"):
+			return cols
+		case tok.kind == sqlWord || tok.kind == sqlIdent:
+			cols = append(cols, strings.ToLower(tok.text))
+		}
+	}
+}
+
+// parseSet follows standard_conforming_strings, which turns off the
+// backslash escapes of the PostgreSQL dumps
+func (d *sqlDump) parseSet() {
+	var b strings.Builder
+	for {
+		tok := d.lx.next()
+		if tok.kind == sqlEOF || tok.is(sqlPunct, ";") {
+			break
+		}
+		b.WriteString(strings.ToLower(tok.text))
+	}
+
+	if s, ok := strings.CutPrefix(b.String(), "standard_conforming_strings"); ok {
+		s = strings.Trim(s, "=")
+		if strings.HasPrefix(s, "to") {
+			s = s[2:]
+		}
+		d.lx.backslash = s != "on"
+	}
+}
+
+// parseCreate registers the columns of CREATE TABLE statements
+func (d *sqlDump) parseCreate() {
+	for {
+		tok := d.lx.next()
+		if tok.kind == sqlEOF || tok.is(sqlPunct, ";") {
+			return
+		}
+		if tok.is(sqlWord, "TABLE") {
+			break
+		}
+	}
+
+	name, tok := d.tableName()
+	if !tok.is(sqlPunct, "(") {
+		d.skipAfter(tok)
+		return
+	}
+
+	cols := []string{}
+	depth := 1
+	start := true
+	for depth > 0 {
+		tok = d.lx.next()
+		if tok.kind == sqlEOF {
+			return
+		}
+		if tok.kind == sqlPunct {
+			switch tok.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			case ",":
+				if depth == 1 {
+					start = true
+					continue
+				}
+			case ";":
+				return
+			}
+		} else if start && depth == 1 && (tok.kind == sqlIdent || !sqlConstraintKeywords[strings.ToUpper(tok.text)]) {
+			cols = append(cols, strings.ToLower(tok.text))
+		}
+		start = false
+	}
+
+	d.tables[name] = cols
+	d.skipStatement()
+}
+
+// parseInsert sends the rows of INSERT INTO ... VALUES (...), (...)
+func (d *sqlDump) parseInsert() {
+	name, tok := d.tableName()
+	cols := d.tables[name]
+	if tok.is(sqlPunct, "(") {
+		cols = d.readColumns()
+		tok = d.lx.next()
+	}
+	if !tok.is(sqlWord, "VALUES") && !tok.is(sqlWord, "VALUE") {
+		d.skipAfter(tok)
+		return
+	}
+
+	row := d.table(name, cols)
+	for {
+		tok = d.lx.next()
+		if !tok.is(sqlPunct, "(") {
+			// ON DUPLICATE KEY UPDATE, RETURNING and alike
+			d.skipAfter(tok)
+			return
+		}
+
+		values, ok := d.readTuple()
+		if !ok {
+			return
+		}
+		if row != nil {
+			row(values, tok.line)
+		}
+
+		tok = d.lx.next()
+		if !tok.is(sqlPunct, ",") {
+			d.skipAfter(tok)
+			return
+		}
+	}
+}
+
+// readTuple reads the values of one row, after its opening parenthesis.
+// NULL is an empty value and the prefixed literals (_binary '...',
+// X'...') are their string.
+func (d *sqlDump) readTuple() ([]string, bool) {
+	values := []string{}
+	depth := 1
+	var word strings.Builder
+	str, quoted := "", false
+
+	add := func() {
+		v := word.String()
+		if quoted {
+			v = str
+		} else if strings.EqualFold(v, "NULL") {
+			v = ""
+		}
+		values = append(values, v)
+		word.Reset()
+		str, quoted = "", false
+	}
+
+	for {
+		tok := d.lx.next()
+		switch tok.kind {
+		case sqlEOF:
+			return values, false
+		case sqlString:
+			str, quoted = tok.text, true
+		case sqlPunct:
+			switch tok.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+				if depth == 0 {
+					add()
+					return values, true
+				}
+			case ",":
+				if depth == 1 {
+					add()
+				}
+			case ";":
+				return values, false
+			}
+		default:
+			word.WriteString(tok.text)
+		}
+	}
+}
+
+// parseCopy sends the rows of the PostgreSQL COPY ... FROM stdin blocks
+// (tab separated, up to the \. line)
+func (d *sqlDump) parseCopy() {
+	name, tok := d.tableName()
+	cols := d.tables[name]
+	if tok.is(sqlPunct, "(") {
+		cols = d.readColumns()
+		tok = d.lx.next()
+	}
+	if !tok.is(sqlWord, "FROM") {
+		d.skipAfter(tok)
+		return
+	}
+
+	tok = d.lx.next()
+	stdin := tok.is(sqlWord, "stdin")
+	d.skipAfter(tok)
+	if !stdin {
+		return
+	}
+
+	// the rest of the COPY line
+	_, _ = d.lx.readLine()
+
+	row := d.table(name, cols)
+	for {
+		line := d.lx.line
+		s, ok := d.lx.readLine()
+		if !ok || s == "\\." {
+			return
+		}
+		if row != nil {
+			row(sqlCopyValues(s), line)
+		}
+	}
+}
+
+// sqlCopyValues splits one row of a COPY block. \N is NULL.
+func sqlCopyValues(line string) []string {
+	values := strings.Split(line, "\t")
+	for i, v := range values {
+		if v == "\\N" {
+			values[i] = ""
+			continue
+		}
+		if !strings.Contains(v, "\\") {
+			continue
+		}
+
+		var b strings.Builder
+		for j := 0; j < len(v); j++ {
+			if v[j] == '\\' && j+1 < len(v) {
+				j++
+				switch v[j] {
+				case 'b':
+					b.WriteByte('\b')
+				case 'f':
+					b.WriteByte('\f')
+				case 'v':
+					b.WriteByte('\v')
+				default:
+					b.WriteByte(sqlUnescape(v[j]))
+				}
+				continue
+			}
+			b.WriteByte(v[j])
+		}
+		values[i] = b.String()
+	}
+	return values
+}
+
+// SQL column kinds
+const (
+	sqlColumnOther = iota
+	sqlColumnEmail
+	sqlColumnUser
+	sqlColumnPassword
+	sqlColumnHash
+	sqlColumnSalt
+)
+
+// Words of column names that are about the user or the password, but are
+// not one (user_id, last_login, password_reset_token, email_verified_at...)
+var sqlIgnoredColumnWords = map[string]bool{
+	"id": true, "at": true, "on": true, "ip": true, "last": true, "date": true,
+	"time": true, "token": true, "reset": true, "expires": true, "expire": true,
+	"expiry": true, "expiration": true, "count": true, "type": true, "status": true,
+	"agent": true, "hint": true, "changed": true, "updated": true, "created": true,
+	"attempts": true, "failed": true, "verified": true, "confirmed": true, "sent": true,
+	"policy": true, "version": true, "algo": true, "algorithm": true, "format": true,
+	"length": true, "key": true, "question": true, "answer": true, "required": true,
+	"enabled": true, "flag": true, "valid": true, "group": true, "role": true,
+}
+
+// sqlColumnKind classifies the column by its name
+func sqlColumnKind(name string) int {
+	n := strings.ToLower(name)
+	words := strings.FieldsFunc(n, func(r rune) bool {
+		return !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'))
+	})
+	for _, w := range words {
+		if sqlIgnoredColumnWords[w] {
+			return sqlColumnOther
+		}
+	}
+	if strings.HasSuffix(n, "id") || strings.HasSuffix(n, "date") {
+		return sqlColumnOther
+	}
+
+	switch {
+	case strings.Contains(n, "salt"):
+		return sqlColumnSalt
+	case strings.Contains(n, "hash"):
+		return sqlColumnHash
+	case strings.Contains(n, "pass") || strings.Contains(n, "pwd") || strings.Contains(n, "senha"):
+		return sqlColumnPassword
+	case strings.Contains(n, "mail"):
+		return sqlColumnEmail
+	case strings.Contains(n, "user") || strings.Contains(n, "login") || strings.Contains(n, "nick") ||
+		n == "account" || n == "uname" || n == "usr" || n == "usuario":
+		return sqlColumnUser
+	}
+	return sqlColumnOther
+}
+
+// sqlColumns is the position of the credential columns of a table (-1
+// when the table has none)
+type sqlColumns struct {
+	email    int
+	user     int
+	password int
+	hash     int
+	salt     int
+}
+
+// newSqlColumns maps the credential columns. The first column of each kind
+// wins.
+func newSqlColumns(columns []string) sqlColumns {
+	c := sqlColumns{-1, -1, -1, -1, -1}
+	for i, col := range columns {
+		var p *int
+		switch sqlColumnKind(col) {
+		case sqlColumnEmail:
+			p = &c.email
+		case sqlColumnUser:
+			p = &c.user
+		case sqlColumnPassword:
+			p = &c.password
+		case sqlColumnHash:
+			p = &c.hash
+		case sqlColumnSalt:
+			p = &c.salt
+		default:
+			continue
+		}
+		if *p < 0 {
+			*p = i
+		}
+	}
+	return c
+}
+
+// hasCredentials tells if the table has a user (or e-mail) and a password
+// (or hash) column
+func (c sqlColumns) hasCredentials() bool {
+	return (c.email >= 0 || c.user >= 0) && (c.password >= 0 || c.hash >= 0)
+}
+
+// value returns the value of the column at i, if the row has it
+func (c sqlColumns) value(values []string, i int) string {
+	if i < 0 || i >= len(values) {
+		return ""
+	}
+	return strings.TrimSpace(values[i])
+}
+
+// Prefixes of the well known password hash formats (crypt, phpass,
+// Django, LDAP)
+var sqlHashPrefixes = []string{
+	"$2a$", "$2b$", "$2x$", "$2y$", "$1$", "$5$", "$6$", "$apr1$", "$argon2",
+	"$P$", "$H$", "$S$", "$pbkdf2", "pbkdf2_", "sha1$", "md5$", "bcrypt$",
+	"{SSHA}", "{SHA}", "{SSHA256}", "{SSHA512}", "{MD5}", "{SMD5}", "{CRYPT}",
+}
+
+// isPasswordHash tells if the password value is a hash: a well known
+// format or a hex digest (MD5, SHA-1, SHA-2)
+func isPasswordHash(v string) bool {
+	for _, p := range sqlHashPrefixes {
+		if strings.HasPrefix(v, p) {
+			return true
+		}
+	}
+
+	switch len(v) {
+	case 32, 40, 56, 64, 96, 128:
+		for i := 0; i < len(v); i++ {
+			c := v[i]
+			if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// sqlCredential builds the credential finding of one row. The hashes are
+// hash:salt (the hashcat notation) when the table has a salt column.
+func sqlCredential(table string, c sqlColumns, values []string) (models.Finding, bool) {
+	u2 := c.value(values, c.email)
+	if !strings.Contains(u2, "@") {
+		if u := c.value(values, c.user); u != "" {
+			u2 = u
+		}
+	}
+
+	p1 := c.value(values, c.password)
+	hashed := false
+	if p1 == "" {
+		p1 = c.value(values, c.hash)
+		hashed = p1 != ""
+	}
+	if u2 == "" || p1 == "" {
+		return models.Finding{}, false
+	}
+
+	description := "SQL dump credential"
+	severity := 100
+	if hashed || isPasswordHash(p1) {
+		description = "SQL dump password hash"
+		severity = 70
+		if salt := c.value(values, c.salt); salt != "" {
+			p1 += ":" + salt
+		}
+	}
+
+	finding := models.Finding{
+		RuleID:      "SQL Dump » " + table,
+		Description: description,
+		Secret:      p1,
+		Entropy:     runner.ShannonEntropy(p1),
+		Match:       u2 + ":" + p1,
+	}
+
+	d1 := ""
+	if strings.Contains(u2, "@") {
+		d1 = strings.ToLower(u2[strings.LastIndex(u2, "@")+1:])
+	} else if e1 := strings.SplitN(u2, "\\", 2); len(e1) == 2 && e1[0] != "" && e1[1] != "" {
+		d1 = e1[0]
+	}
+
+	cpf := ""
+	if ok, c1 := tools.ExtractCPF(u2); ok {
+		cpf = c1
+	}
+
+	finding.Credential = models.Credential{
+		UserDomain: d1,
+		Username:   u2,
+		Password:   p1,
+		Severity:   severity,
+		Entropy:    finding.Entropy,
+		CPF:        cpf,
+	}
+	return finding, true
+}
+
+// parseSqlDump maps the user, e-mail, password, hash and salt columns of
+// the dump tables into credentials. The rule is the table name. The
+// e-mails, URLs and secrets are left to the rules.
+func (run *TextParser) parseSqlDump(thisRunner *runner.Runner, result *models.File, file runner.FileItem) error {
+	f, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cnt := 0
+	err = readSqlDump(f, func(table string, columns []string) func(values []string, line int) {
+		c := newSqlColumns(columns)
+		if !c.hasCredentials() {
+			return nil
+		}
+
+		return func(values []string, line int) {
+			finding, ok := sqlCredential(table, c, values)
+			if !ok {
+				return
+			}
+			finding.StartLine = line
+			finding.EndLine = line
+			// the row values, the dump comments are not kept
+			finding.Line = strings.Join(values, "\t")
+			if thisRunner.AddParsedFinding(result, finding) {
+				cnt++
+			}
+		}
+	})
+
+	run.log.Debug("SQL dump parsed", "file", file.Name(), "credentials", cnt)
+	return err
+}
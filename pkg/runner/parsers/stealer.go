@@ -0,0 +1,523 @@
+package driver
+
+import (
+	"bufio"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/h2non/filetype"
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/database"
+	"github.com/helviojunior/intelparser/pkg/models"
+	"github.com/helviojunior/intelparser/pkg/runner"
+	"gorm.io/gorm"
+)
+
+// Files holding the saved browser passwords (URL/Username/Password blocks)
+var StealerPasswordFiles = []string{
+	"passwords.txt",
+	"all passwords.txt",
+	"_allpasswords_list.txt",
+}
+
+// Files holding the infected machine information
+var StealerInfoFiles = []string{
+	"userinformation.txt",
+	"system info.txt",
+	"system_info.txt",
+	"information.txt",
+}
+
+var StealerCookieFolders = []string{
+	"cookies",
+}
+
+var StealerAutofillFolders = []string{
+	"autofills",
+	"autofill",
+}
+
+var stealerDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	"02.01.2006 15:04:05",
+	"02/01/2006 15:04:05",
+	"1/2/2006 3:04:05 PM",
+	"2006-01-02",
+}
+
+// StealerInfo is the infected machine data read from the information file
+type StealerInfo struct {
+	Path     string
+	Name     string
+	Date     time.Time
+	IP       string
+	Country  string
+	Computer string
+	User     string
+	HWID     string
+}
+
+// StealerParser is a driver that parses stealer logs (RedLine, Raccoon,
+// Lumma and alike) grouping the results per infected machine.
+type StealerParser struct {
+	// options for the Runner to consider
+	options runner.Options
+	// logger
+	log *slog.Logger
+	// Infected machines
+	info []StealerInfo
+	//
+	conn *gorm.DB
+	//
+	infoMutex sync.Mutex
+}
+
+// NewStealer returns a new StealerParser instance
+func NewStealer(logger *slog.Logger, opts runner.Options) (*StealerParser, error) {
+	var conn *gorm.DB
+	var err error
+	conn, err = database.Connection(opts.Writer.GlobalDbURI, true, false)
+	if err != nil {
+		logger.Debug("Error connecting to the database", "conn", opts.Writer.GlobalDbURI, "err", err)
+		conn = nil
+	}
+
+	return &StealerParser{
+		options:   opts,
+		log:       logger,
+		info:      []StealerInfo{},
+		conn:      conn,
+		infoMutex: sync.Mutex{},
+	}, nil
+}
+
+// IsStealerFolder returns true if the folder looks like an infected machine
+// root folder (it has a passwords or an information file)
+func IsStealerFolder(folder_path string) bool {
+	entries, err := os.ReadDir(folder_path)
+	if err != nil {
+		return false
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		n := strings.ToLower(e.Name())
+		if tools.SliceHasStr(StealerPasswordFiles, n) || tools.SliceHasStr(StealerInfoFiles, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddVictim registers an infected machine folder, reading its information
+// file (if any). Must be called before sending the folder files to the runner.
+func (run *StealerParser) AddVictim(folder_path string) error {
+	info := StealerInfo{
+		Path: filepath.Clean(folder_path),
+		Name: filepath.Base(folder_path),
+		Date: time.Now(),
+	}
+
+	if fst, err := os.Stat(folder_path); err == nil {
+		info.Date = fst.ModTime()
+	}
+
+	entries, err := os.ReadDir(folder_path)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() && tools.SliceHasStr(StealerInfoFiles, strings.ToLower(e.Name())) {
+			if err := run.ParseInfo(filepath.Join(folder_path, e.Name()), &info); err != nil {
+				run.log.Debug("Error parsing information file", "file", e.Name(), "err", err)
+			}
+			break
+		}
+	}
+
+	run.infoMutex.Lock()
+	defer run.infoMutex.Unlock()
+	run.info = append(run.info, info)
+
+	return nil
+}
+
+// ParseInfo reads the 'Key: Value' lines of a stealer information file
+func (run *StealerParser) ParseInfo(file_path string, info *StealerInfo) error {
+	f, err := os.Open(file_path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		s1 := strings.SplitN(scanner.Text(), ":", 2)
+		if len(s1) != 2 {
+			continue
+		}
+
+		k := strings.ToLower(strings.Trim(s1[0], " -*\t\r\uFEFF"))
+		v := strings.Trim(s1[1], " \t\r")
+		if v == "" || strings.EqualFold(v, "unknown") {
+			continue
+		}
+
+		switch k {
+		case "ip", "ip address", "ipaddress":
+			info.IP = v
+		case "country", "country code":
+			info.Country = v
+		case "computer", "computer name", "computername", "machinename", "machine name", "pc name", "hostname":
+			info.Computer = v
+		case "username", "user name", "user":
+			info.User = v
+		case "hwid", "machineid", "machine id":
+			info.HWID = v
+		case "log date", "date", "local date", "install date":
+			for _, layout := range stealerDateLayouts {
+				if dt, err := time.Parse(layout, v); err == nil {
+					info.Date = dt
+					break
+				}
+			}
+		}
+	}
+
+	if info.Computer != "" {
+		info.Name = info.Computer
+		if info.User != "" {
+			info.Name += "\\" + info.User
+		}
+	}
+
+	return scanner.Err()
+}
+
+// getVictim returns the registered infected machine owning the file
+func (run *StealerParser) getVictim(file_path string) *StealerInfo {
+	run.infoMutex.Lock()
+	defer run.infoMutex.Unlock()
+
+	var victim *StealerInfo
+	fp := filepath.Clean(file_path)
+	for i := range run.info {
+		if strings.HasPrefix(fp, run.info[i].Path+string(os.PathSeparator)) {
+			if victim == nil || len(run.info[i].Path) > len(victim.Path) {
+				victim = &run.info[i]
+			}
+		}
+	}
+	return victim
+}
+
+// ParseFile parses one file of an infected machine folder
+func (run *StealerParser) ParseFile(thisRunner *runner.Runner, file runner.FileItem) (*models.File, error) {
+	logger := run.log.With("file_path", file.RealPath)
+
+	file_name_ext := filepath.Base(file.RealPath)
+	var (
+		result = &models.File{
+			Provider:  "Stealer",
+			FilePath:  file.RealPath,
+			FileName:  file_name_ext,
+			Name:      file.VirtualPath,
+			Date:      time.Now(),
+			IndexedAt: time.Now(),
+			Bucket:    "Stealer » Unknown",
+		}
+	)
+
+	victim := run.getVictim(file.RealPath)
+	if victim != nil {
+		result.Date = victim.Date
+		result.Bucket = "Stealer » " + victim.Name
+		result.ProviderId = filepath.Base(victim.Path)
+		if victim.HWID != "" {
+			result.ProviderId = victim.HWID
+		}
+	} else {
+		logger.Warn("File is not inside a stealer log folder")
+	}
+
+	if fst, err := os.Stat(file.RealPath); err == nil {
+		result.Size = uint(fst.Size())
+	}
+
+	result.Fingerprint, _ = tools.GetHashFromFile(file.RealPath)
+	result.MIMEType, _ = tools.GetMimeType(file.RealPath)
+
+	// Screenshots, wallets and other binaries have nothing to be parsed
+	if kind, err := filetype.MatchFile(file.RealPath); err == nil && kind != filetype.Unknown {
+		logger.Debug("Ignoring binary file", "mime", kind.MIME.Value)
+		return nil, nil
+	}
+
+	if run.conn != nil {
+		response := run.conn.Raw("SELECT count(id) as count from files WHERE failed = false AND file_name = ? AND fingerprint = ?", file_name_ext, result.Fingerprint)
+		if response != nil {
+			var cnt int
+			_ = response.Row().Scan(&cnt)
+			if cnt > 0 {
+				logger.Debug("[File already parsed]")
+				return nil, nil
+			}
+		}
+	}
+
+	logger = run.log.With("file", file_name_ext)
+	logger.Debug("Parsing file")
+
+	n := strings.ToLower(file_name_ext)
+	parent := strings.ToLower(filepath.Base(filepath.Dir(file.RealPath)))
+	switch {
+	case tools.SliceHasStr(StealerPasswordFiles, n):
+		cnt, err := run.ParsePasswords(thisRunner, result)
+		if err != nil {
+			return result, err
+		}
+		if cnt == 0 {
+			// Unknown block format, fallback to the generic rules
+			if err := thisRunner.DetectFile(result); err != nil {
+				return result, err
+			}
+		}
+
+	case tools.SliceHasStr(StealerCookieFolders, parent):
+		if err := run.ParseCookies(thisRunner, result); err != nil {
+			return result, err
+		}
+
+	case tools.SliceHasStr(StealerInfoFiles, n):
+		if err := thisRunner.DetectFile(result); err != nil {
+			return result, err
+		}
+		result.Content, _ = tools.ReadTextFile(result.FilePath)
+
+	default:
+		if err := thisRunner.DetectFile(result); err != nil {
+			return result, err
+		}
+		if tools.SliceHasStr(StealerAutofillFolders, parent) {
+			result.Content, _ = tools.ReadTextFile(result.FilePath)
+		}
+	}
+
+	result.FilePath = file.VirtualPath
+
+	return result, nil
+}
+
+// ParsePasswords reads the URL/Username/Password blocks of a passwords file,
+// returning the number of credentials found
+func (run *StealerParser) ParsePasswords(thisRunner *runner.Runner, result *models.File) (int, error) {
+	f, err := os.Open(result.FilePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var (
+		cnt    = 0
+		lineNo = 0
+		start  = 0
+		u1     string
+		u2     string
+		p1     string
+		block  []string
+	)
+
+	flush := func() {
+		if u2 != "" || p1 != "" {
+			if finding, ok := stealerCredential(u1, u2, p1); ok {
+				finding.StartLine = start
+				finding.EndLine = lineNo
+				finding.Line = strings.Join(block, "\n")
+				if thisRunner.AddParsedFinding(result, finding) {
+					cnt++
+				}
+			}
+		}
+		u1, u2, p1 = "", "", ""
+		start = 0
+		block = block[:0]
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.Trim(scanner.Text(), " \t\r\uFEFF")
+
+		// Empty lines and separators (===, ---, ***) close the block
+		if line == "" || strings.Trim(line, "=-*_ ") == "" {
+			flush()
+			continue
+		}
+
+		s1 := strings.SplitN(line, ":", 2)
+		if len(s1) != 2 {
+			continue
+		}
+
+		k := strings.ToLower(strings.Trim(s1[0], " \t"))
+		v := strings.Trim(s1[1], " \t")
+		if strings.EqualFold(v, "unknown") {
+			v = ""
+		}
+
+		switch k {
+		case "url", "host", "hostname", "site":
+			if u1 != "" {
+				flush()
+			}
+			u1 = v
+		case "user", "username", "login", "email":
+			u2 = v
+		case "pass", "password":
+			p1 = v
+		default:
+			continue
+		}
+
+		if start == 0 {
+			start = lineNo
+		}
+		block = append(block, line)
+	}
+	flush()
+
+	return cnt, scanner.Err()
+}
+
+// ParseCookies reads the Netscape formatted cookies file, reporting
+// one URL per cookie domain
+func (run *StealerParser) ParseCookies(thisRunner *runner.Runner, result *models.File) error {
+	f, err := os.Open(result.FilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	lineNo := 0
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lineNo++
+		s1 := strings.Split(scanner.Text(), "\t")
+		if len(s1) < 7 {
+			continue
+		}
+
+		// "#HttpOnly_" marks the HttpOnly cookies (e.g. the sessions), not
+		// a comment
+		d1 := strings.TrimPrefix(strings.Trim(s1[0], " \uFEFF"), "#HttpOnly_")
+		d1 = strings.ToLower(strings.Trim(d1, ". "))
+		if d1 == "" || strings.HasPrefix(d1, "#") || seen[d1] {
+			continue
+		}
+		seen[d1] = true
+
+		if ok, _ := runner.ContainsUrlDomainStopWord(d1); ok {
+			continue
+		}
+
+		finding := models.Finding{
+			RuleID:    "Stealer » Cookies",
+			StartLine: lineNo,
+			EndLine:   lineNo,
+			Line:      scanner.Text(),
+			Url: models.URL{
+				Domain: d1,
+				Url:    "https://" + d1 + "/",
+			},
+		}
+		thisRunner.AddParsedFinding(result, finding)
+	}
+
+	return scanner.Err()
+}
+
+// stealerCredential builds the finding for one URL/Username/Password block
+func stealerCredential(u1 string, u2 string, p1 string) (models.Finding, bool) {
+	finding := models.Finding{
+		RuleID:      "Stealer » Passwords",
+		Description: "Stealer log saved password",
+		Secret:      p1,
+		Entropy:     runner.ShannonEntropy(p1),
+	}
+
+	if u2 == "" {
+		return finding, false
+	}
+
+	var d1 string
+	var d2 string
+	if strings.Contains(u2, "@") {
+		if m, err := mail.ParseAddress(strings.ToLower(u2)); err == nil {
+			d1 = strings.SplitN(m.Address, "@", 2)[1]
+			if ok, _ := runner.ContainsEmailDomainStopWord(d1); !ok {
+				finding.Email = models.Email{
+					Domain: d1,
+					Email:  m.Address,
+				}
+			}
+		}
+	} else if strings.Contains(u2, "\\") {
+		e1 := strings.SplitN(u2, "\\", 2)
+		if e1[0] != "" && e1[1] != "" {
+			d1 = e1[0]
+		}
+	}
+
+	if u1 != "" {
+		if u, err := url.Parse(u1); err == nil {
+			d2 = strings.ToLower(u.Hostname())
+			if (u.Scheme == "http" || u.Scheme == "https") && d2 != "" {
+				if ok, _ := runner.ContainsUrlDomainStopWord(d2); !ok {
+					finding.Url = models.URL{
+						Domain: d2,
+						Url:    u1,
+					}
+				}
+			}
+		}
+	}
+
+	cpf := ""
+	if ok, c := tools.ExtractCPF(u2); ok {
+		cpf = c
+	}
+
+	finding.Credential = models.Credential{
+		UserDomain: d1,
+		Username:   u2,
+		Password:   p1,
+		Url:        u1,
+		UrlDomain:  d2,
+		Severity:   100,
+		Entropy:    finding.Entropy,
+		CPF:        cpf,
+	}
+	finding.Match = u1 + " " + u2 + ":" + p1
+
+	return finding, true
+}
+
+func (run *StealerParser) Close() {
+	run.log.Debug("closing Stealer parser context")
+}
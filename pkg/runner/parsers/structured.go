@@ -0,0 +1,524 @@
+package driver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/models"
+	"github.com/helviojunior/intelparser/pkg/runner"
+	"gopkg.in/yaml.v3"
+)
+
+// StructuredExtensions are the extensions of the structured files. Other
+// files are structured only by their content: JSON, or a header delimited
+// by anything but ':' (so "Email: x@y.com" lines of a paste are not one).
+var StructuredExtensions = []string{".csv", ".tsv", ".json", ".jsonl", ".ndjson"}
+
+// IsStructured tells if the file is a structured file, by its name
+func IsStructured(file_name string) bool {
+	ext := strings.ToLower(filepath.Ext(file_name))
+	for _, e := range StructuredExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Columns is the synonym table of the structured files (CSV, TSV and
+// JSON): the header names (or JSON key paths, like user.email) of each
+// field, in priority order
+//
+//	email = ["correo"]
+//	username = ["usr"]
+//	password = ["clave"]
+//	url = ["sitio"]
+type Columns struct {
+	Email    []string `toml:"email" yaml:"email"`
+	Username []string `toml:"username" yaml:"username"`
+	Password []string `toml:"password" yaml:"password"`
+	Url      []string `toml:"url" yaml:"url"`
+}
+
+// DefaultColumns returns the built-in synonym table
+func DefaultColumns() *Columns {
+	return &Columns{
+		Email: []string{
+			"email", "e_mail", "mail", "email_address", "emailaddress", "mail_address",
+			"user_email", "useremail", "correo", "e_mail_address",
+		},
+		Username: []string{
+			"username", "user_name", "user", "login", "user_login", "userlogin",
+			"account", "account_name", "nickname", "nick", "uname", "usuario", "usr",
+		},
+		Password: []string{
+			"password", "pass", "passwd", "pwd", "senha", "contrasena", "clave",
+			"password_hash", "passwordhash", "pass_hash", "hash",
+		},
+		Url: []string{
+			"url", "uri", "link", "website", "site", "host", "hostname", "domain",
+			"origin_url", "action_url", "login_url", "target", "application",
+		},
+	}
+}
+
+// LoadColumnsFile reads a TOML (default) or YAML (.yaml/.yml) synonym
+// file. Its entries come first, then the built-in ones.
+func LoadColumnsFile(file_path string) (*Columns, error) {
+	data, err := os.ReadFile(file_path)
+	if err != nil {
+		return nil, err
+	}
+
+	cf := &Columns{}
+	switch strings.ToLower(filepath.Ext(file_path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cf)
+	default:
+		err = toml.Unmarshal(data, cf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(file_path), err)
+	}
+
+	def := DefaultColumns()
+	return &Columns{
+		Email:    append(cf.Email, def.Email...),
+		Username: append(cf.Username, def.Username...),
+		Password: append(cf.Password, def.Password...),
+		Url:      append(cf.Url, def.Url...),
+	}, nil
+}
+
+// columnName normalizes a header name or a JSON key path
+func columnName(name string) string {
+	name = strings.ToLower(strings.Trim(name, " \t\r\n\"'\uFEFF"))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// structuredRow is the mapped fields of one row
+type structuredRow struct {
+	email    string
+	username string
+	password string
+	url      string
+}
+
+// structuredHeader is the position of the mapped columns of a CSV header
+// (-1 when the file has none)
+type structuredHeader struct {
+	email    int
+	username int
+	password int
+	url      int
+
+	// values is set when a header cell is a value (an e-mail), so the
+	// first line is data and not a header
+	values bool
+}
+
+// mapHeader maps the CSV header with the synonym table
+func (c *Columns) mapHeader(header []string) structuredHeader {
+	names := map[string]int{}
+	values := false
+	for i, h := range header {
+		if strings.Contains(h, "@") {
+			values = true
+		}
+		if n := columnName(h); n != "" {
+			if _, ok := names[n]; !ok {
+				names[n] = i
+			}
+		}
+	}
+
+	find := func(synonyms []string) int {
+		for _, s := range synonyms {
+			if i, ok := names[columnName(s)]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	return structuredHeader{
+		email:    find(c.Email),
+		username: find(c.Username),
+		password: find(c.Password),
+		url:      find(c.Url),
+		values:   values,
+	}
+}
+
+// ok tells if the header is a real one: no values, at least 2 mapped
+// columns and an e-mail column, or a username and a password ones
+func (h structuredHeader) ok() bool {
+	mapped := 0
+	for _, i := range []int{h.email, h.username, h.password, h.url} {
+		if i >= 0 {
+			mapped++
+		}
+	}
+	return !h.values && mapped >= 2 && (h.email >= 0 || (h.username >= 0 && h.password >= 0))
+}
+
+func (h structuredHeader) row(record []string) structuredRow {
+	value := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	return structuredRow{
+		email:    value(h.email),
+		username: value(h.username),
+		password: value(h.password),
+		url:      value(h.url),
+	}
+}
+
+// mapObject maps the flattened JSON object (key path -> value). A synonym
+// matches the whole key path or its last key.
+func (c *Columns) mapObject(obj map[string]string) structuredRow {
+	last := map[string]string{}
+	paths := make([]string, 0, len(obj))
+	for p := range obj {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		k := p[strings.LastIndex(p, ".")+1:]
+		if _, ok := last[k]; !ok {
+			last[k] = obj[p]
+		}
+	}
+
+	find := func(synonyms []string) string {
+		for _, s := range synonyms {
+			s = columnName(s)
+			if v, ok := obj[s]; ok && v != "" {
+				return v
+			}
+			if v, ok := last[s]; ok && v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	return structuredRow{
+		email:    find(c.Email),
+		username: find(c.Username),
+		password: find(c.Password),
+		url:      find(c.Url),
+	}
+}
+
+// flattenJSON flattens the nested objects and arrays into key paths
+// (user.email, emails.0)
+func flattenJSON(prefix string, v interface{}, obj map[string]string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, v1 := range t {
+			p := columnName(k)
+			if prefix != "" {
+				p = prefix + "." + p
+			}
+			flattenJSON(p, v1, obj)
+		}
+	case []interface{}:
+		for i, v1 := range t {
+			flattenJSON(fmt.Sprintf("%s.%d", prefix, i), v1, obj)
+		}
+	case nil:
+	case string:
+		obj[prefix] = strings.TrimSpace(t)
+	default:
+		obj[prefix] = strings.TrimSpace(fmt.Sprint(t))
+	}
+}
+
+// sniffDelimiter returns the most frequent delimiter of the header line
+func sniffDelimiter(header string) rune {
+	delim := rune(0)
+	max := 0
+	for _, d := range []rune{',', '\t', ';', '|', ':'} {
+		if n := strings.Count(header, string(d)); n > max {
+			delim = d
+			max = n
+		}
+	}
+	return delim
+}
+
+// structuredFinding builds the finding of one row: a credential when it
+// has a password, plus the e-mail and the URL
+func structuredFinding(rule string, row structuredRow) (models.Finding, bool) {
+	finding := models.Finding{
+		RuleID:      rule,
+		Description: "Structured breach file row",
+	}
+
+	u2 := row.username
+	if u2 == "" {
+		u2 = row.email
+	}
+
+	var d1 string
+	var d2 string
+	if e := row.email; strings.Contains(e, "@") {
+		if m, err := mail.ParseAddress(strings.ToLower(e)); err == nil {
+			d1 = strings.SplitN(m.Address, "@", 2)[1]
+			if ok, _ := runner.ContainsEmailDomainStopWord(d1); !ok {
+				finding.Email = models.Email{
+					Domain: d1,
+					Email:  m.Address,
+				}
+			}
+		}
+	}
+	if strings.Contains(u2, "@") {
+		d1 = strings.ToLower(u2[strings.LastIndex(u2, "@")+1:])
+	} else if e1 := strings.SplitN(u2, "\\", 2); len(e1) == 2 && e1[0] != "" && e1[1] != "" {
+		d1 = e1[0]
+	}
+
+	u1 := row.url
+	if u1 != "" && !strings.Contains(u1, "://") {
+		// A bare host name (site, domain)
+		u1 = "https://" + strings.Trim(u1, "/") + "/"
+	}
+	if u1 != "" {
+		if u, err := url.Parse(u1); err == nil {
+			d2 = strings.ToLower(u.Hostname())
+			if (u.Scheme == "http" || u.Scheme == "https") && d2 != "" {
+				if ok, _ := runner.ContainsUrlDomainStopWord(d2); !ok {
+					finding.Url = models.URL{
+						Domain: d2,
+						Url:    u1,
+					}
+				}
+			}
+		}
+		if d2 == "" {
+			u1 = ""
+		}
+	}
+
+	if p1 := row.password; u2 != "" && p1 != "" {
+		severity := 100
+		if isPasswordHash(p1) {
+			severity = 70
+		}
+
+		cpf := ""
+		if ok, c := tools.ExtractCPF(u2); ok {
+			cpf = c
+		}
+
+		finding.Secret = p1
+		finding.Entropy = runner.ShannonEntropy(p1)
+		finding.Match = strings.TrimSpace(u1 + " " + u2 + ":" + p1)
+		finding.Credential = models.Credential{
+			UserDomain: d1,
+			Username:   u2,
+			Password:   p1,
+			Url:        u1,
+			UrlDomain:  d2,
+			Severity:   severity,
+			Entropy:    finding.Entropy,
+			CPF:        cpf,
+		}
+	}
+
+	ok := finding.Credential.Username != "" || finding.Email.Email != "" || finding.Url.Url != ""
+	return finding, ok
+}
+
+// lineCounter keeps the offsets of the line breaks read from r, so the
+// offsets of the JSON decoder can be turned into line numbers
+type lineCounter struct {
+	r        io.Reader
+	read     int64
+	newlines []int64
+	line     int
+}
+
+func (lc *lineCounter) Read(p []byte) (int, error) {
+	n, err := lc.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\n' {
+			lc.newlines = append(lc.newlines, lc.read+int64(i))
+		}
+	}
+	lc.read += int64(n)
+	return n, err
+}
+
+// lineAt returns the line of the offset. The offsets must not decrease
+// between calls.
+func (lc *lineCounter) lineAt(offset int64) int {
+	for len(lc.newlines) > 0 && lc.newlines[0] < offset {
+		lc.newlines = lc.newlines[1:]
+		lc.line++
+	}
+	return lc.line + 1
+}
+
+// parseStructured parses CSV, TSV and JSON (array or JSON lines) files
+// whose header (or first object) maps to known columns, with one finding
+// per row. The line rules are not run over them, so the line regexes do not
+// mangle the columns (the caller still runs the secret rules). Returns
+// false for any other file.
+func (run *TextParser) parseStructured(thisRunner *runner.Runner, result *models.File, file runner.FileItem) (bool, error) {
+	f, err := file.Open()
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	lc := &lineCounter{r: f}
+	br := bufio.NewReaderSize(lc, 64*1024)
+	head, _ := br.Peek(64 * 1024)
+	bom := int64(0)
+	if bytes.HasPrefix(head, []byte("\uFEFF")) {
+		bom = 3
+		_, _ = br.Discard(3)
+		head = head[3:]
+	}
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	if len(trimmed) == 0 {
+		return false, nil
+	}
+
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		return run.parseJSONRows(thisRunner, result, br, lc, bom)
+	}
+
+	header := string(head)
+	if i := strings.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+	delim := sniffDelimiter(header)
+	if delim == 0 || (delim == ':' && !IsStructured(file.Name())) {
+		return false, nil
+	}
+
+	cr := csv.NewReader(br)
+	cr.Comma = delim
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	record, err := cr.Read()
+	if err != nil {
+		return false, nil
+	}
+	h := run.columns.mapHeader(record)
+	if !h.ok() {
+		return false, nil
+	}
+	size := len(record)
+
+	rule := "Structured » CSV"
+	if delim == '\t' {
+		rule = "Structured » TSV"
+	}
+	run.log.Debug("Parsing structured file", "file", file.Name(), "delimiter", string(delim), "columns", size)
+
+	for {
+		record, err = cr.Read()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				continue
+			}
+			return true, err
+		}
+
+		// user:pass:with:colons, the extra fields are of the last column
+		if delim == ':' && len(record) > size && size > 0 {
+			record = append(record[:size-1], strings.Join(record[size-1:], ":"))
+		}
+
+		finding, ok := structuredFinding(rule, h.row(record))
+		if !ok {
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		finding.StartLine = line
+		finding.EndLine = line
+		finding.Line = strings.Join(record, string(delim))
+		thisRunner.AddParsedFinding(result, finding)
+	}
+}
+
+// parseJSONRows parses a JSON array of objects, or a stream of objects
+// (JSON lines). The decoder offsets start after the BOM, if any.
+func (run *TextParser) parseJSONRows(thisRunner *runner.Runner, result *models.File, br *bufio.Reader, lc *lineCounter, bom int64) (bool, error) {
+	dec := json.NewDecoder(br)
+
+	// A top level array: its elements are the rows
+	if b, _ := br.Peek(1); len(b) > 0 && b[0] == '[' {
+		if _, err := dec.Token(); err != nil {
+			return false, nil
+		}
+	}
+
+	parsed := false
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if !parsed {
+				return false, nil
+			}
+			return true, err
+		}
+		end := bom + dec.InputOffset()
+		start := lc.lineAt(end - int64(len(raw)))
+
+		var v interface{}
+		vd := json.NewDecoder(bytes.NewReader(raw))
+		vd.UseNumber()
+		if err := vd.Decode(&v); err != nil {
+			continue
+		}
+
+		obj := map[string]string{}
+		flattenJSON("", v, obj)
+		row := run.columns.mapObject(obj)
+
+		if !parsed {
+			// The first object decides if the file is known
+			if row.email == "" && (row.username == "" || row.password == "") {
+				return false, nil
+			}
+			parsed = true
+		}
+
+		finding, ok := structuredFinding("Structured » JSON", row)
+		if !ok {
+			continue
+		}
+		finding.StartLine = start
+		finding.EndLine = lc.lineAt(end)
+		finding.Line = string(raw)
+		thisRunner.AddParsedFinding(result, finding)
+	}
+
+	return parsed, nil
+}
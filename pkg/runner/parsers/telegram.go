@@ -0,0 +1,169 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/helviojunior/intelparser/pkg/runner"
+)
+
+// TelegramExportFile is the file name of the Telegram Desktop export JSON
+const TelegramExportFile = "result.json"
+
+// TelegramMessageFile is the file name of the message text, under the
+// message virtual path
+const TelegramMessageFile = "message.txt"
+
+// TelegramExport is the Telegram Desktop export JSON (result.json), of one
+// chat (channel or group) or of the whole account (chats.list)
+type TelegramExport struct {
+	TelegramChat
+
+	Chats struct {
+		List []TelegramChat `json:"list"`
+	} `json:"chats"`
+}
+
+// TelegramChat is one exported chat (channel, group or private chat)
+type TelegramChat struct {
+	Id       int64             `json:"id"`
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Messages []TelegramMessage `json:"messages"`
+}
+
+// TelegramMessage is one post of the chat. The attached document (if
+// downloaded) is at File, relative to the export folder.
+type TelegramMessage struct {
+	Id           int64           `json:"id"`
+	Type         string          `json:"type"`
+	Date         string          `json:"date"`
+	DateUnixtime string          `json:"date_unixtime"`
+	From         string          `json:"from"`
+	File         string          `json:"file"`
+	FileName     string          `json:"file_name"`
+	MimeType     string          `json:"mime_type"`
+	Text         json.RawMessage `json:"text"`
+}
+
+// ReadTelegramExport reads the chats of the export JSON
+func ReadTelegramExport(file_path string) ([]TelegramChat, error) {
+	f, err := os.Open(file_path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	export := TelegramExport{}
+	if err = json.NewDecoder(f).Decode(&export); err != nil {
+		return nil, fmt.Errorf("invalid Telegram export %s: %w", file_path, err)
+	}
+
+	chats := export.Chats.List
+	if len(export.Messages) > 0 || export.Name != "" {
+		chats = append([]TelegramChat{export.TelegramChat}, chats...)
+	}
+	if len(chats) == 0 {
+		return nil, errors.New("no chats found in the Telegram export " + file_path)
+	}
+	return chats, nil
+}
+
+// ChatName returns the chat name (or id, when it has no name)
+func (chat TelegramChat) ChatName() string {
+	if name := strings.TrimSpace(chat.Name); name != "" {
+		return name
+	}
+	return strconv.FormatInt(chat.Id, 10)
+}
+
+// Time returns the message timestamp
+func (msg TelegramMessage) Time() time.Time {
+	if ts, err := strconv.ParseInt(msg.DateUnixtime, 10, 64); err == nil && ts > 0 {
+		return time.Unix(ts, 0).UTC()
+	}
+	if dt, err := time.Parse("2006-01-02T15:04:05", msg.Date); err == nil {
+		return dt
+	}
+	return time.Time{}
+}
+
+// Content returns the message text. The text is a string, or a list of
+// strings and formatted entities ({"type": "link", "text": "..."}).
+func (msg TelegramMessage) Content() string {
+	if len(msg.Text) == 0 {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(msg.Text, &s); err == nil {
+		return s
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(msg.Text, &parts); err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, p := range parts {
+		entity := struct {
+			Text string `json:"text"`
+		}{}
+		if err := json.Unmarshal(p, &s); err == nil {
+			b.WriteString(s)
+		} else if err := json.Unmarshal(p, &entity); err == nil {
+			b.WriteString(entity.Text)
+		}
+	}
+	return b.String()
+}
+
+// HasFile tells if the attached document was downloaded with the export
+func (msg TelegramMessage) HasFile() bool {
+	return msg.File != "" && !strings.HasPrefix(msg.File, "(")
+}
+
+// TelegramParser is a driver that runs all rules over the messages and the
+// attached documents (including archives) of Telegram Desktop exports
+type TelegramParser struct {
+	*TextParser
+}
+
+// NewTelegram returns a new TelegramParser instance
+func NewTelegram(logger *slog.Logger, opts runner.Options) (*TelegramParser, error) {
+	tp, err := NewText(logger, opts, TextMeta{Provider: "Telegram"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TelegramParser{
+		TextParser: tp,
+	}, nil
+}
+
+// AddMessage registers the leak information of the message files (the
+// text and the attached document) under virtual_path: the chat name is
+// the bucket, the message timestamp is the date and the message id is the
+// provider id
+func (run *TelegramParser) AddMessage(virtual_path string, chat TelegramChat, msg TelegramMessage) {
+	meta := TextMeta{
+		Bucket:     chat.ChatName(),
+		ProviderId: strconv.FormatInt(msg.Id, 10),
+	}
+	if dt := msg.Time(); !dt.IsZero() {
+		meta.Date = dt.Format(time.RFC3339)
+		meta.date = dt
+	}
+	run.AddSidecar(virtual_path, meta)
+}
+
+func (run *TelegramParser) Close() {
+	run.log.Debug("closing Telegram parser context")
+}
@@ -0,0 +1,364 @@
+package driver
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/h2non/filetype"
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/database"
+	"github.com/helviojunior/intelparser/pkg/extract"
+	"github.com/helviojunior/intelparser/pkg/models"
+	"github.com/helviojunior/intelparser/pkg/runner"
+	"gorm.io/gorm"
+)
+
+// TextSidecarExt is the extension of the sidecar JSON with the leak
+// information of a file (e.g. combo.txt.meta.json)
+const TextSidecarExt = ".meta.json"
+
+// TextStdinName is the file name of the documents read from stdin
+const TextStdinName = "stdin"
+
+// TextMeta is the leak information of plain-text files (combolists,
+// pastes and alike), supplied by flags or by a sidecar JSON
+type TextMeta struct {
+	Bucket   string `json:"bucket"`
+	Provider string `json:"provider"`
+	Date     string `json:"date"`
+	Name     string `json:"name"`
+
+	// Id of the file on the provider (e.g. a message id). Default: the
+	// file name
+	ProviderId string `json:"provider_id"`
+
+	// Parsed Date
+	date time.Time
+}
+
+// TextParser is a driver that runs all rules over arbitrary files, with no
+// Info.csv needed
+type TextParser struct {
+	// options for the Runner to consider
+	options runner.Options
+	// logger
+	log *slog.Logger
+	// Default leak information
+	meta TextMeta
+	// Sidecar leak information, by virtual path
+	sidecars map[string]TextMeta
+	// Column synonyms of the structured files (CSV, TSV and JSON)
+	columns *Columns
+	//
+	conn *gorm.DB
+	//
+	sidecarMutex sync.Mutex
+}
+
+// NewText returns a new TextParser instance
+func NewText(logger *slog.Logger, opts runner.Options, meta TextMeta) (*TextParser, error) {
+	var conn *gorm.DB
+	var err error
+
+	if meta.Provider == "" {
+		meta.Provider = "Text"
+	}
+	if meta.Bucket == "" {
+		meta.Bucket = "Unknown"
+	}
+	if err = meta.parseDate(); err != nil {
+		return nil, err
+	}
+
+	columns := DefaultColumns()
+	if opts.Parser.ColumnsFile != "" {
+		if columns, err = LoadColumnsFile(opts.Parser.ColumnsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err = database.Connection(opts.Writer.GlobalDbURI, true, false)
+	if err != nil {
+		logger.Debug("Error connecting to the database", "conn", opts.Writer.GlobalDbURI, "err", err)
+		conn = nil
+	}
+
+	return &TextParser{
+		options:      opts,
+		log:          logger,
+		meta:         meta,
+		sidecars:     map[string]TextMeta{},
+		columns:      columns,
+		conn:         conn,
+		sidecarMutex: sync.Mutex{},
+	}, nil
+}
+
+// ReadTextMeta reads a sidecar JSON file
+func ReadTextMeta(file_path string) (TextMeta, error) {
+	meta := TextMeta{}
+
+	data, err := os.ReadFile(file_path)
+	if err != nil {
+		return meta, err
+	}
+	if err = json.Unmarshal(data, &meta); err != nil {
+		return meta, err
+	}
+
+	return meta, meta.parseDate()
+}
+
+// Merge returns the leak information with the non empty fields of other
+// replacing the ones of meta
+func (meta TextMeta) Merge(other TextMeta) TextMeta {
+	if other.Bucket != "" {
+		meta.Bucket = other.Bucket
+	}
+	if other.Provider != "" {
+		meta.Provider = other.Provider
+	}
+	if other.Date != "" {
+		meta.Date = other.Date
+		meta.date = other.date
+	}
+	if other.Name != "" {
+		meta.Name = other.Name
+	}
+	if other.ProviderId != "" {
+		meta.ProviderId = other.ProviderId
+	}
+	return meta
+}
+
+func (meta *TextMeta) parseDate() error {
+	meta.date = time.Time{}
+	if meta.Date == "" {
+		return nil
+	}
+
+	for _, layout := range stealerDateLayouts {
+		if dt, err := time.Parse(layout, meta.Date); err == nil {
+			meta.date = dt
+			return nil
+		}
+	}
+	return errors.New("invalid leak date: " + meta.Date)
+}
+
+// AddSidecar registers the leak information of the file (or archive) at
+// virtual_path. Must be called before sending the files to the runner.
+func (run *TextParser) AddSidecar(virtual_path string, meta TextMeta) {
+	run.sidecarMutex.Lock()
+	defer run.sidecarMutex.Unlock()
+
+	vp := filepath.ToSlash(virtual_path)
+	if _, ok := run.sidecars[vp]; !ok {
+		run.sidecars[vp] = meta
+	}
+}
+
+// getMeta returns the leak information of the file, the one of the
+// closest sidecar or the default one
+func (run *TextParser) getMeta(virtual_path string) TextMeta {
+	run.sidecarMutex.Lock()
+	defer run.sidecarMutex.Unlock()
+
+	// The file itself, then its folders from the closest one
+	p := filepath.ToSlash(virtual_path)
+	for {
+		if meta, ok := run.sidecars[p]; ok {
+			return run.meta.Merge(meta)
+		}
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			return run.meta
+		}
+		p = p[:i]
+	}
+}
+
+// ParseFile runs all rules over one file
+func (run *TextParser) ParseFile(thisRunner *runner.Runner, file runner.FileItem) (*models.File, error) {
+	logger := run.log.With("file_path", file.Path())
+
+	meta := run.getMeta(file.VirtualPath)
+	file_name_ext := file.Name()
+	if file.IsReader() {
+		file_name_ext = TextStdinName
+	}
+	var (
+		result = &models.File{
+			Provider:   meta.Provider,
+			FilePath:   file.Path(),
+			FileName:   file_name_ext,
+			Name:       file.VirtualPath,
+			Date:       meta.date,
+			IndexedAt:  time.Now(),
+			Bucket:     meta.Provider + " » " + meta.Bucket,
+			ProviderId: file_name_ext,
+		}
+	)
+
+	if meta.Name != "" {
+		result.Name = meta.Name
+	}
+	if meta.ProviderId != "" {
+		result.ProviderId = meta.ProviderId
+	}
+
+	// Mailbox parts: the message origin
+	file.SetMail(result)
+	if file.Mail != nil && file.Mail.MessageId != "" {
+		result.ProviderId = file.Mail.MessageId
+	}
+
+	if result.Date.IsZero() {
+		result.Date = time.Now()
+		if !file.IsStream() {
+			if fst, err := os.Stat(file.RealPath); err == nil {
+				result.Date = fst.ModTime()
+			}
+		}
+	}
+
+	if file.IsReader() {
+		return run.parseReader(thisRunner, result, file)
+	}
+
+	if size, err := file.Size(); err == nil {
+		result.Size = uint(size)
+	}
+
+	result.Fingerprint, _ = file.Fingerprint()
+	result.MIMEType, _ = file.MimeType()
+
+	// Images and other binaries have nothing to be parsed, except the Office
+	// and PDF documents, whose text is extracted
+	if r, err := file.Open(); err == nil {
+		header := make([]byte, 262)
+		n, _ := io.ReadFull(r, header)
+		r.Close()
+		if kind, err := filetype.Match(header[:n]); err == nil && kind != filetype.Unknown &&
+			extract.Detect(file_name_ext, header[:n]) == extract.FormatUnknown {
+			logger.Debug("Ignoring binary file", "mime", kind.MIME.Value)
+			return nil, nil
+		}
+	}
+
+	if run.alreadyParsed(result) {
+		logger.Debug("[File already parsed]")
+		return nil, nil
+	}
+
+	logger = run.log.With("file", file_name_ext)
+	logger.Debug("Parsing file")
+
+	// CSV, TSV and JSON files with known columns: one finding per row
+	structured := false
+	if !IsSqlDump(file_name_ext) {
+		var err error
+		if structured, err = run.parseStructured(thisRunner, result, file); err != nil {
+			return result, err
+		}
+	}
+
+	if !structured {
+		if err := thisRunner.DetectFileItem(result, file); err != nil {
+			return result, err
+		}
+	} else if err := thisRunner.DetectSecrets(result, file); err != nil {
+		// the tokens and keys of the other columns
+		return result, err
+	}
+
+	// Database dumps: the credentials of the INSERT and COPY rows
+	if IsSqlDump(file_name_ext) {
+		if err := run.parseSqlDump(thisRunner, result, file); err != nil {
+			return result, err
+		}
+	}
+
+	result.FilePath = file.VirtualPath
+
+	return result, nil
+}
+
+// parseReader runs all rules over a single pass content (e.g. stdin). The
+// fingerprint is computed while the content is read, so the findings are
+// added only after the control database check.
+func (run *TextParser) parseReader(thisRunner *runner.Runner, result *models.File, file runner.FileItem) (*models.File, error) {
+	logger := run.log.With("file", file.VirtualPath)
+	logger.Debug("Parsing file")
+
+	hash := sha1.New()
+	counter := &countWriter{}
+	reader := bufio.NewReader(io.TeeReader(file.Reader, io.MultiWriter(hash, counter)))
+
+	header, _ := reader.Peek(512)
+	if kind, err := filetype.Match(header); err == nil && kind != filetype.Unknown {
+		logger.Debug("Ignoring binary content", "mime", kind.MIME.Value)
+		_, _ = io.Copy(io.Discard, reader)
+		return nil, nil
+	}
+	result.MIMEType, _ = tools.GetMimeTypeFromReader(bytes.NewReader(header))
+
+	findings, err := thisRunner.DetectReader(reader, 100)
+	result.Fingerprint = hex.EncodeToString(hash.Sum(nil))
+	result.Size = uint(counter.n)
+	result.FilePath = file.VirtualPath
+	if err != nil {
+		return result, err
+	}
+
+	if run.alreadyParsed(result) {
+		logger.Debug("[File already parsed]")
+		return nil, nil
+	}
+
+	for _, finding := range findings {
+		thisRunner.AddFinding(result, finding)
+	}
+
+	return result, nil
+}
+
+// alreadyParsed checks the control database for the file fingerprint
+func (run *TextParser) alreadyParsed(result *models.File) bool {
+	if run.conn == nil {
+		return false
+	}
+
+	response := run.conn.Raw("SELECT count(id) as count from files WHERE failed = false AND file_name = ? AND fingerprint = ?", result.FileName, result.Fingerprint)
+	if response != nil {
+		var cnt int
+		_ = response.Row().Scan(&cnt)
+		return cnt > 0
+	}
+	return false
+}
+
+// countWriter counts the bytes written to it
+type countWriter struct {
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+func (run *TextParser) Close() {
+	run.log.Debug("closing Text parser context")
+}
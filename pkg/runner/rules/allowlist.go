@@ -0,0 +1,192 @@
+package rules
+
+import (
+    "fmt"
+    "path/filepath"
+    re "regexp"
+    "strings"
+
+    "github.com/helviojunior/intelparser/pkg/models"
+    "github.com/helviojunior/intelparser/pkg/readers"
+)
+
+// Allowlist suppresses findings of a rule (or of all rules, when global)
+type Allowlist struct {
+    Description string
+
+    // Regexes are matched against the RegexTarget: "secret" (default),
+    // "match" or "line"
+    Regexes     []*re.Regexp
+    RegexTarget string
+
+    // Paths are matched against the file path
+    Paths       []*re.Regexp
+
+    // StopWords are matched (contains) against the secret
+    StopWords   []string
+
+    // Values are exact (case insensitive) secrets, usernames, e-mails or
+    // URLs to be ignored
+    Values      map[string]struct{}
+}
+
+// AllowlistConfig is the [allowlist] (global) or [[rules.allowlists]] entry
+// of the rules file
+type AllowlistConfig struct {
+    Description string   `toml:"description" yaml:"description"`
+    Regexes     []string `toml:"regexes" yaml:"regexes"`
+    RegexTarget string   `toml:"regexTarget" yaml:"regexTarget"`
+    Paths       []string `toml:"paths" yaml:"paths"`
+    StopWords   []string `toml:"stopwords" yaml:"stopwords"`
+    Values      []string `toml:"values" yaml:"values"`
+    ValuesFile  string   `toml:"valuesFile" yaml:"valuesFile"`
+}
+
+// NewAllowlist returns an empty Allowlist
+func NewAllowlist() *Allowlist {
+    return &Allowlist{
+        Regexes:   []*re.Regexp{},
+        Paths:     []*re.Regexp{},
+        StopWords: []string{},
+        Values:    make(map[string]struct{}),
+    }
+}
+
+// Compile builds the Allowlist described by the config. Relative values
+// files are resolved from base_path.
+func (ac AllowlistConfig) Compile(base_path string) (*Allowlist, error) {
+    a := NewAllowlist()
+    a.Description = ac.Description
+    a.RegexTarget = strings.ToLower(ac.RegexTarget)
+
+    if !(a.RegexTarget == "" || a.RegexTarget == "secret" || a.RegexTarget == "match" || a.RegexTarget == "line") {
+        return nil, fmt.Errorf("invalid allowlist regexTarget %q", ac.RegexTarget)
+    }
+
+    for _, s := range ac.Regexes {
+        r, err := re.Compile(s)
+        if err != nil {
+            return nil, fmt.Errorf("invalid allowlist regex: %w", err)
+        }
+        a.Regexes = append(a.Regexes, r)
+    }
+
+    for _, s := range ac.Paths {
+        r, err := re.Compile(s)
+        if err != nil {
+            return nil, fmt.Errorf("invalid allowlist path: %w", err)
+        }
+        a.Paths = append(a.Paths, r)
+    }
+
+    for _, s := range ac.StopWords {
+        a.StopWords = append(a.StopWords, strings.ToLower(s))
+    }
+
+    a.AddValues(ac.Values)
+
+    if ac.ValuesFile != "" {
+        fp := ac.ValuesFile
+        if !filepath.IsAbs(fp) && base_path != "" {
+            fp = filepath.Join(base_path, fp)
+        }
+        if err := a.LoadValuesFile(fp); err != nil {
+            return nil, err
+        }
+    }
+
+    return a, nil
+}
+
+// LoadValuesFile adds the exact values (one per line) of the file
+func (a *Allowlist) LoadValuesFile(file_path string) error {
+    values := []string{}
+    if err := readers.ReadFileList(file_path, &values); err != nil {
+        return err
+    }
+    a.AddValues(values)
+    return nil
+}
+
+func (a *Allowlist) AddValues(values []string) {
+    for _, v := range values {
+        v = strings.ToLower(strings.Trim(v, " \r\n\t"))
+        if v != "" {
+            a.Values[v] = struct{}{}
+        }
+    }
+}
+
+// IsEmpty returns true if the allowlist has nothing to check
+func (a *Allowlist) IsEmpty() bool {
+    return len(a.Regexes) == 0 && len(a.Paths) == 0 && len(a.StopWords) == 0 && len(a.Values) == 0
+}
+
+// PathAllowed returns true if the path matches one of the allowlist paths
+func (a *Allowlist) PathAllowed(path string) bool {
+    if path == "" {
+        return false
+    }
+    for _, r := range a.Paths {
+        if r.MatchString(path) {
+            return true
+        }
+    }
+    return false
+}
+
+// RegexAllowed returns true if the finding regex target matches one of the
+// allowlist regexes
+func (a *Allowlist) RegexAllowed(finding *models.Finding) bool {
+    target := finding.Secret
+    switch a.RegexTarget {
+    case "match":
+        target = finding.Match
+    case "line":
+        target = finding.Line
+    }
+
+    for _, r := range a.Regexes {
+        if r.MatchString(target) {
+            return true
+        }
+    }
+    return false
+}
+
+// ContainsStopWord returns true if the secret contains one of the allowlist
+// stopwords
+func (a *Allowlist) ContainsStopWord(s string) (bool, string) {
+    s = strings.ToLower(s)
+    for _, stopWord := range a.StopWords {
+        if strings.Contains(s, stopWord) {
+            return true, stopWord
+        }
+    }
+    return false, ""
+}
+
+// ValueAllowed returns true if the secret or any of the entities extracted
+// by the rule is one of the allowlist exact values
+func (a *Allowlist) ValueAllowed(finding *models.Finding) bool {
+    if len(a.Values) == 0 {
+        return false
+    }
+
+    for _, v := range []string{
+        finding.Secret,
+        finding.Credential.Username,
+        finding.Credential.Password,
+        finding.Email.Email,
+        finding.Url.Url,
+        finding.SecretEntity.Value,
+    } {
+        if v == "" {
+            continue
+        }
+        if _, ok := a.Values[strings.ToLower(v)]; ok {
+            return true
+        }
+    }
+    return false
+}
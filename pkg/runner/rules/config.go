@@ -0,0 +1,448 @@
+package rules
+
+import (
+    "fmt"
+    "net/mail"
+    "net/url"
+    "os"
+    "path/filepath"
+    re "regexp"
+    "strconv"
+    "strings"
+
+    "github.com/BurntSushi/toml"
+    "github.com/helviojunior/intelparser/internal/tools"
+    "github.com/helviojunior/intelparser/pkg/models"
+    "gopkg.in/yaml.v3"
+)
+
+// RulesFile is the declarative rules file (TOML or YAML)
+//
+//  [[rules]]
+//  id = "Leak4 » Email;Pass"
+//  regex = '''(?i)([a-z0-9._-]+@[a-z0-9.-]+\.[a-z]{2,});([^\s;]{3,})'''
+//  secretGroup = 2
+//  keywords = ["@", ";"]
+//
+//  [rules.map]
+//  "credential.username" = "$1 | email"
+//  "credential.password" = "$2"
+//  "email.email" = "$1 | email"
+type RulesFile struct {
+    Rules     []RuleConfig     `toml:"rules" yaml:"rules"`
+
+    // Allowlist applied to all rules
+    Allowlist *AllowlistConfig `toml:"allowlist" yaml:"allowlist"`
+
+    // Folder of the rules file, used to resolve relative paths
+    basePath  string
+}
+
+// RuleConfig is one rule entry of the rules file. A rule with the same id
+// of a built-in rule replaces it, and `disabled = true` removes it. An entry
+// with only id and allowlists extends the allowlists of an existing rule.
+type RuleConfig struct {
+    RuleID              string            `toml:"id" yaml:"id"`
+    Description         string            `toml:"description" yaml:"description"`
+    Regex               string            `toml:"regex" yaml:"regex"`
+    SecretGroup         int               `toml:"secretGroup" yaml:"secretGroup"`
+    Entropy             float64           `toml:"entropy" yaml:"entropy"`
+    Keywords            []string          `toml:"keywords" yaml:"keywords"`
+    Path                string            `toml:"path" yaml:"path"`
+    Tags                []string          `toml:"tags" yaml:"tags"`
+    CheckGlobalStopWord bool              `toml:"stopwords" yaml:"stopwords"`
+    Disabled            bool              `toml:"disabled" yaml:"disabled"`
+
+    // Map is the post-processor: target field -> template
+    Map                 map[string]string `toml:"map" yaml:"map"`
+
+    Allowlists          []AllowlistConfig `toml:"allowlists" yaml:"allowlists"`
+}
+
+// Fields a rule map can fill
+var RuleMapTargets = []string{
+    "credential.username",
+    "credential.password",
+    "credential.url",
+    "credential.user_domain",
+    "credential.url_domain",
+    "credential.cpf",
+    "credential.severity",
+    "email.email",
+    "url.url",
+    "secret.type",
+    "secret.value",
+}
+
+// Filters available to the rule map templates (e.g. "$1 | lower | email")
+var RuleMapFilters = []string{
+    "lower",
+    "upper",
+    "trim",
+    "urldecode",
+    "email",
+    "url",
+    "domain",
+}
+
+var templateGroupRegexp = re.MustCompile(`\$(\d+|\{[a-zA-Z0-9_]+\})`)
+
+type ruleMapping struct {
+    target   string
+    template string
+    filters  []string
+}
+
+// LoadRulesFile reads a TOML (default) or YAML (.yaml/.yml) rules file
+func LoadRulesFile(file_path string) (*RulesFile, error) {
+    data, err := os.ReadFile(file_path)
+    if err != nil {
+        return nil, err
+    }
+
+    rf := &RulesFile{
+        basePath: filepath.Dir(file_path),
+    }
+    switch strings.ToLower(filepath.Ext(file_path)) {
+    case ".yaml", ".yml":
+        err = yaml.Unmarshal(data, rf)
+    default:
+        err = toml.Unmarshal(data, rf)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", filepath.Base(file_path), err)
+    }
+
+    return rf, nil
+}
+
+// Apply compiles the file rules over the current rule list. Rules with an
+// existing id replace the current ones, disabled rules are removed and new
+// ones are appended.
+func (rf *RulesFile) Apply(current []*Rule) ([]*Rule, error) {
+    result := append([]*Rule{}, current...)
+
+    for _, rc := range rf.Rules {
+        idx := -1
+        for i, r := range result {
+            if r.RuleID == rc.RuleID {
+                idx = i
+                break
+            }
+        }
+
+        if rc.Disabled {
+            if idx >= 0 {
+                result = append(result[:idx], result[idx+1:]...)
+            }
+            continue
+        }
+
+        if idx >= 0 && rc.Regex == "" && rc.Path == "" && len(rc.Allowlists) > 0 {
+            // Only extends the allowlists, keeping the rule as is
+            r := *result[idx]
+            r.Allowlists = append([]*Allowlist{}, r.Allowlists...)
+            for _, ac := range rc.Allowlists {
+                a, err := ac.Compile(rf.basePath)
+                if err != nil {
+                    return nil, fmt.Errorf("%s: %w", r.RuleID, err)
+                }
+                r.Allowlists = append(r.Allowlists, a)
+            }
+            result[idx] = &r
+            continue
+        }
+
+        r, err := rc.Compile(rf.basePath)
+        if err != nil {
+            return nil, err
+        }
+
+        if idx >= 0 {
+            result[idx] = r
+        }else{
+            result = append(result, r)
+        }
+    }
+
+    return result, nil
+}
+
+// GlobalAllowlist compiles the global allowlist of the file (nil if absent)
+func (rf *RulesFile) GlobalAllowlist() (*Allowlist, error) {
+    if rf.Allowlist == nil {
+        return nil, nil
+    }
+    return rf.Allowlist.Compile(rf.basePath)
+}
+
+// Compile builds and validates the Rule described by the config. Relative
+// allowlist values files are resolved from base_path.
+func (rc RuleConfig) Compile(base_path string) (*Rule, error) {
+    var err error
+    r := &Rule{
+        RuleID:              strings.TrimSpace(rc.RuleID),
+        Description:         rc.Description,
+        Entropy:             rc.Entropy,
+        SecretGroup:         rc.SecretGroup,
+        Tags:                rc.Tags,
+        Keywords:            rc.Keywords,
+        CheckGlobalStopWord: rc.CheckGlobalStopWord,
+    }
+
+    if rc.Regex != "" {
+        if r.Regex, err = re.Compile(rc.Regex); err != nil {
+            return nil, fmt.Errorf("%s: invalid regex: %w", r.RuleID, err)
+        }
+    }
+
+    if rc.Path != "" {
+        if r.Path, err = re.Compile(rc.Path); err != nil {
+            return nil, fmt.Errorf("%s: invalid path: %w", r.RuleID, err)
+        }
+    }
+
+    if err = r.Validate(); err != nil {
+        return nil, err
+    }
+
+    for _, ac := range rc.Allowlists {
+        a, err := ac.Compile(base_path)
+        if err != nil {
+            return nil, fmt.Errorf("%s: %w", r.RuleID, err)
+        }
+        r.Allowlists = append(r.Allowlists, a)
+    }
+
+    if r.Regex == nil {
+        // Path only rule, nothing to map
+        r.PostProcessor = func(finding *models.Finding) (bool, error) { return true, nil }
+        return r, nil
+    }
+
+    if len(rc.Map) == 0 {
+        return nil, fmt.Errorf("%s: |map| is empty, this rule will have no effect", r.RuleID)
+    }
+
+    mappings := []ruleMapping{}
+    for target, expr := range rc.Map {
+        m, err := parseRuleMapping(strings.ToLower(target), expr, r.Regex)
+        if err != nil {
+            return nil, fmt.Errorf("%s: %w", r.RuleID, err)
+        }
+        mappings = append(mappings, m)
+    }
+
+    r.PostProcessor = mappingPostProcessor(r.Regex, mappings)
+    return r, nil
+}
+
+// parseRuleMapping parses a "template | filter | filter" expression
+func parseRuleMapping(target string, expr string, regex *re.Regexp) (ruleMapping, error) {
+    if !tools.SliceHasStr(RuleMapTargets, target) {
+        return ruleMapping{}, fmt.Errorf("unknown map target %q", target)
+    }
+
+    parts := strings.Split(expr, "|")
+    m := ruleMapping{
+        target:   target,
+        template: strings.TrimSpace(parts[0]),
+        filters:  []string{},
+    }
+
+    for _, f := range parts[1:] {
+        f = strings.ToLower(strings.TrimSpace(f))
+        if !tools.SliceHasStr(RuleMapFilters, f) {
+            return m, fmt.Errorf("unknown filter %q at map target %q", f, target)
+        }
+        m.filters = append(m.filters, f)
+    }
+
+    names := regex.SubexpNames()
+    for _, g := range templateGroupRegexp.FindAllStringSubmatch(m.template, -1) {
+        ref := strings.Trim(g[1], "{}")
+        if n, err := strconv.Atoi(ref); err == nil {
+            if n > regex.NumSubexp() {
+                return m, fmt.Errorf("invalid group $%d at map target %q, max regex group %d", n, target, regex.NumSubexp())
+            }
+        } else if !tools.SliceHasStr(names, ref) {
+            return m, fmt.Errorf("unknown named group %q at map target %q", ref, target)
+        }
+    }
+
+    return m, nil
+}
+
+// eval renders the template with the regex groups and applies the filters
+func (m ruleMapping) eval(groups []string, names []string) (string, error) {
+    v := templateGroupRegexp.ReplaceAllStringFunc(m.template, func(s string) string {
+        ref := strings.Trim(s[1:], "{}")
+        if n, err := strconv.Atoi(ref); err == nil {
+            if n < len(groups) {
+                return groups[n]
+            }
+            return ""
+        }
+        for i, name := range names {
+            if name == ref && i < len(groups) {
+                return groups[i]
+            }
+        }
+        return ""
+    })
+
+    for _, f := range m.filters {
+        if v == "" {
+            break
+        }
+        switch f {
+        case "lower":
+            v = strings.ToLower(v)
+        case "upper":
+            v = strings.ToUpper(v)
+        case "trim":
+            v = strings.Trim(v, " \r\n\t\"'")
+        case "urldecode":
+            if s, err := url.QueryUnescape(v); err == nil {
+                v = s
+            }
+        case "email":
+            e1 := strings.ToLower(strings.Replace(strings.Trim(v, ". "), "%40", "@", -1))
+            e1 = strings.Replace(e1, ".@", "@", -1)
+            e1 = strings.Replace(e1, "@.", "@", -1)
+            a, err := mail.ParseAddress(e1)
+            if err != nil {
+                return "", err
+            }
+            v = a.Address
+        case "url":
+            v = normalizeUrl(v)
+            if _, err := url.Parse(v); err != nil {
+                return "", err
+            }
+        case "domain":
+            if strings.Contains(v, "://") {
+                u, err := url.Parse(v)
+                if err != nil {
+                    return "", err
+                }
+                v = u.Hostname()
+            } else if strings.Contains(v, "@") {
+                v = v[strings.LastIndex(v, "@")+1:]
+            }
+            v = strings.ToLower(v)
+        }
+    }
+
+    return v, nil
+}
+
+// mappingPostProcessor builds the PostProcessor of a declarative rule
+func mappingPostProcessor(regex *re.Regexp, mappings []ruleMapping) func(*models.Finding) (bool, error) {
+    names := regex.SubexpNames()
+
+    return func(finding *models.Finding) (bool, error) {
+        groups := regex.FindStringSubmatch(finding.Match)
+        if groups == nil {
+            return false, nil
+        }
+
+        values := make(map[string]string)
+        for _, m := range mappings {
+            v, err := m.eval(groups, names)
+            if err != nil {
+                return false, err
+            }
+            values[m.target] = v
+        }
+
+        if u1 := values["url.url"]; u1 != "" {
+            u, err := url.Parse(u1)
+            if err != nil {
+                return false, err
+            }
+            finding.Url = models.URL{
+                Domain      : strings.ToLower(u.Hostname()),
+                Url         : u1,
+            }
+        }
+
+        if e1 := values["email.email"]; e1 != "" {
+            m, err := mail.ParseAddress(e1)
+            if err != nil {
+                return false, err
+            }
+            finding.Email = models.Email{
+                Domain      : strings.SplitN(m.Address, "@", 2)[1],
+                Email       : m.Address,
+            }
+        }
+
+        if s1 := values["secret.value"]; s1 != "" {
+            finding.SecretEntity = models.Secret{
+                Type        : values["secret.type"],
+                Value       : s1,
+                Entropy     : finding.Entropy,
+            }
+            if finding.SecretEntity.Type == "" {
+                finding.SecretEntity.Type = "generic"
+            }
+        }
+
+        if u2 := values["credential.username"]; u2 != "" {
+            cred := models.Credential{
+                Username    : u2,
+                Password    : values["credential.password"],
+                Url         : values["credential.url"],
+                UserDomain  : values["credential.user_domain"],
+                UrlDomain   : values["credential.url_domain"],
+                CPF         : values["credential.cpf"],
+                Severity    : 100,
+                Entropy     : finding.Entropy,
+            }
+
+            if s := values["credential.severity"]; s != "" {
+                sev, err := strconv.Atoi(s)
+                if err != nil {
+                    return false, err
+                }
+                cred.Severity = sev
+            }
+
+            if cred.UserDomain == "" {
+                if strings.Contains(u2, "@") {
+                    cred.UserDomain = strings.ToLower(u2[strings.LastIndex(u2, "@")+1:])
+                } else if e1 := strings.SplitN(u2, "\\", 2); len(e1) == 2 && e1[0] != "" && e1[1] != "" {
+                    cred.UserDomain = e1[0]
+                }
+            }
+
+            if cred.UrlDomain == "" && cred.Url != "" {
+                if u, err := url.Parse(cred.Url); err == nil {
+                    cred.UrlDomain = strings.ToLower(u.Hostname())
+                }
+            }
+
+            if cred.CPF == "" {
+                if ok, c := tools.ExtractCPF(u2); ok {
+                    cred.CPF = c
+                }
+            }
+
+            finding.Credential = cred
+        }
+
+        return true, nil
+    }
+}
+
+// normalizeUrl fixes the duplicated schemes commonly found in leaks
+func normalizeUrl(u1 string) string {
+    u1 = strings.Replace(u1, "http://http://", "http://", -1)
+    u1 = strings.Replace(u1, "https://https://", "https://", -1)
+    u1 = strings.Replace(u1, "http://https://", "https://", -1)
+    u1 = strings.Replace(u1, "https://http://", "http://", -1)
+    u1 = strings.Replace(u1, "http://http:", "http://", -1)
+    u1 = strings.Replace(u1, "https://https", "https://", -1)
+    return u1
+}
@@ -42,6 +42,9 @@ type Rule struct {
 
     CheckGlobalStopWord bool
 
+    // Allowlists suppress findings of this rule
+    Allowlists []*Allowlist
+
     PostProcessor func(*models.Finding) (bool, error)
 }
 
@@ -58,7 +61,7 @@ func (r Rule) Validate() error {
         } else if r.Description != "" {
             context = ", description: " + r.Description
         }
-        return fmt.Errorf("rule |id| is missing or empty" + context)
+        return fmt.Errorf("rule |id| is missing or empty%s", context)
     }
 
     // Ensure the rule actually matches something.
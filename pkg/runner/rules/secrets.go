@@ -0,0 +1,142 @@
+package rules
+
+import (
+    "encoding/base64"
+    "net/url"
+    re "regexp"
+    "strings"
+    "time"
+
+    "github.com/helviojunior/intelparser/pkg/models"
+)
+
+// Secrets returns the rule pack of API tokens, keys and connection strings
+// of the common providers
+func Secrets() []*Rule {
+    return []*Rule{
+        secretRule("AWS Access Key", "aws_access_key",
+            `\b((?:A3T[A-Z0-9]|AKIA|ASIA|ABIA|ACCA)[A-Z2-7]{16})\b`,
+            3, []string{"A3T", "AKIA", "ASIA", "ABIA", "ACCA"}),
+        secretRule("AWS Secret Key", "aws_secret_key",
+            `(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})(?:[^A-Za-z0-9/+=]|$)`,
+            3, []string{"aws"}),
+        secretRule("GitHub Token", "github_token",
+            `\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36})\b`,
+            3, []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_"}),
+        secretRule("GitHub Fine-Grained Token", "github_token",
+            `\b(github_pat_[A-Za-z0-9_]{82})\b`,
+            3, []string{"github_pat_"}),
+        secretRule("GitLab Token", "gitlab_token",
+            `\b(glpat-[A-Za-z0-9_-]{20})\b`,
+            3, []string{"glpat-"}),
+        secretRule("Slack Token", "slack_token",
+            `\b(xox[abposr]-[0-9A-Za-z-]{10,250})\b`,
+            3, []string{"xoxa-", "xoxb-", "xoxp-", "xoxo-", "xoxs-", "xoxr-"}),
+        secretRule("Slack Webhook", "slack_webhook",
+            `(https://hooks\.slack\.com/(?:services|workflows)/[A-Za-z0-9+/]{43,56})`,
+            0, []string{"hooks.slack.com"}),
+        secretRule("Discord Webhook", "discord_webhook",
+            `(https://(?:ptb\.|canary\.)?discord(?:app)?\.com/api/webhooks/[0-9]{17,20}/[A-Za-z0-9_-]{60,68})`,
+            0, []string{"/api/webhooks/"}),
+        secretRule("Stripe Key", "stripe_key",
+            `\b((?:sk|rk)_(?:live|test)_[0-9A-Za-z]{24,99})\b`,
+            3, []string{"sk_live_", "sk_test_", "rk_live_", "rk_test_"}),
+        secretRule("Google API Key", "google_api_key",
+            `\b(AIza[0-9A-Za-z_-]{35})(?:[^0-9A-Za-z_-]|$)`,
+            3, []string{"AIza"}),
+        secretRule("OpenAI Key", "openai_key",
+            `\b(sk-(?:proj-|svcacct-|admin-)?[A-Za-z0-9_-]{20,74}T3BlbkFJ[A-Za-z0-9_-]{20,74})\b`,
+            3, []string{"T3BlbkFJ"}),
+        secretRule("SendGrid Key", "sendgrid_key",
+            `\b(SG\.[A-Za-z0-9_-]{22}\.[A-Za-z0-9_-]{43})\b`,
+            3, []string{"SG."}),
+        secretRule("NPM Token", "npm_token",
+            `\b(npm_[A-Za-z0-9]{36})\b`,
+            3, []string{"npm_"}),
+        secretRule("Telegram Bot Token", "telegram_bot_token",
+            `\b([0-9]{8,10}:AA[0-9A-Za-z_-]{33})(?:[^0-9A-Za-z_-]|$)`,
+            3, []string{":AA"}),
+        jwtRule(),
+        privateKeyRule(),
+        connectionStringRule(),
+    }
+}
+
+// secretRule builds a rule where the first regex group is the secret
+func secretRule(name string, secretType string, regex string, entropy float64, keywords []string) *Rule {
+    return &Rule{
+        RuleID:      "Secret » " + name,
+        Description: "Extract " + name + " secrets",
+        Regex:       re.MustCompile(regex),
+        Entropy:     entropy,
+        SecretGroup: 1,
+        Keywords:    keywords,
+        Tags:        []string{"secret", secretType},
+        CheckGlobalStopWord: false,
+        PostProcessor : func(finding *models.Finding) (bool, error) {
+            finding.SecretEntity = models.Secret{
+                Time        : time.Now(),
+                Type        : secretType,
+                Value       : finding.Secret,
+                Entropy     : finding.Entropy,
+            }
+            return true, nil
+        },
+    }
+}
+
+func jwtRule() *Rule {
+    r := secretRule("JWT", "jwt",
+        `\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`,
+        3, []string{"eyJ"})
+
+    post := r.PostProcessor
+    r.PostProcessor = func(finding *models.Finding) (bool, error) {
+        // The header must be a JSON with the signing algorithm
+        header, err := base64.RawURLEncoding.DecodeString(strings.SplitN(finding.Secret, ".", 2)[0])
+        if err != nil || !strings.Contains(string(header), `"alg"`) {
+            return false, nil
+        }
+        return post(finding)
+    }
+    return r
+}
+
+func privateKeyRule() *Rule {
+    r := secretRule("Private Key", "private_key",
+        `(-----BEGIN[ A-Z0-9_-]{0,100}PRIVATE KEY(?: BLOCK)?-----[\s\S]{64,}?-----END[ A-Z0-9_-]{0,100}PRIVATE KEY(?: BLOCK)?-----)`,
+        0, []string{"PRIVATE KEY"})
+    r.Description = "Extract private keys (PEM)"
+    return r
+}
+
+func connectionStringRule() *Rule {
+    r := secretRule("Connection String", "connection_string",
+        `(?i)\b((?:postgres(?:ql)?|mysql|mariadb|mongodb(?:\+srv)?|rediss?|amqps?|mssql|sqlserver)://[^\s:/@"'<>]+:[^\s@"'<>]+@[^\s/"'<>]+[^\s"'<>]*)`,
+        0, []string{"://"})
+    r.Description = "Extract database connection strings with credentials"
+
+    post := r.PostProcessor
+    r.PostProcessor = func(finding *models.Finding) (bool, error) {
+        u, err := url.Parse(finding.Secret)
+        if err != nil || u.User == nil {
+            return false, nil
+        }
+
+        // The embedded credential is stored as well
+        if p, ok := u.User.Password(); ok && u.User.Username() != "" {
+            finding.Credential = models.Credential{
+                Time        : time.Now(),
+                Username    : u.User.Username(),
+                Password    : p,
+                Url         : u.Scheme + "://" + u.Host,
+                UrlDomain   : strings.ToLower(u.Hostname()),
+                Severity    : 100,
+                Entropy     : finding.Entropy,
+            }
+        }
+
+        return post(finding)
+    }
+    return r
+}
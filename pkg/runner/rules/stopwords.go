@@ -8,6 +8,8 @@ package rules
 // https://github.com/UraniumX92/Discord-Bot-using-py/tree/224b2b71a58c25f420ce980f2ea49627b4b646f1/Data%20Files
 // https://github.com/Meen11/BSBI-Indexing/blob/63032017aa24f3111f18468607cd0db5997bb891/datasets/citeseer/11/10.1.1.27.6385.txt
 
+// EmailDomainStopWords and UrlDomainStopWords drop the entities of these
+// domains and of their subdomains
 var EmailDomainStopWords = []string{
 	//"gmail",
 	//"hotmail",
@@ -35,7 +37,7 @@ var UrlDomainStopWords = []string{
 	"facebook.com",
 	"youtube.com",
 	"twitter.com",
-	".x.com",
+	"x.com",
 	//"yahoo.com",
 	//"terra.com",
 	//"bol.com",
@@ -12,7 +12,7 @@ import (
 	"strings"
 	"math"
 	//"math/rand/v2"
-    "path/filepath"
+    //"path/filepath"
 	"regexp"
 	"bufio"
 	"bytes"
@@ -24,6 +24,8 @@ import (
 
 	"github.com/h2non/filetype"
 	"github.com/helviojunior/intelparser/internal/ascii"
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/extract"
 	"github.com/helviojunior/intelparser/pkg/models"
 	"github.com/helviojunior/intelparser/pkg/writers"
 	ahocorasick "github.com/BobuSumisu/aho-corasick"
@@ -47,6 +49,9 @@ type Credential struct {
 type Identifiers struct {
 	Rules       []*rules.Rule
 	Keywords    map[string]struct{}
+
+	// Allowlist applied to all rules
+	Allowlist   *rules.Allowlist
 }
 
 // Runner is a runner that probes web targets using a driver
@@ -78,11 +83,21 @@ type Runner struct {
 	// matching given a set of words (keywords from the rules in the config)
 	prefilter ahocorasick.Trie
 
+	// watchlist keeps only the findings of the watched domains, e-mails
+	// and keywords (nil keeps all of them)
+	watchlist *Watchlist
+
+	// clients tags the findings with the client they belong to (nil
+	// leaves them untagged)
+	clients *Clients
+
 	// MaxDecodeDepths limits how many recursive decoding passes are allowed
 	MaxDecodeDepth int
 
 	// files larger than this will be skipped
 	MaxTargetMegaBytes int
+
+	suppressedMutex sync.Mutex
 }
 
 type Status struct {
@@ -91,13 +106,31 @@ type Status struct {
     Url int
     Email int
     Credential int
+    Secret int
 	Skipped int
+	Suppressed Suppressed
 	Spin string
 	Running bool
     IsTerminal bool
     log *slog.Logger
 }
 
+// Suppressed counts the findings dropped by allowlists, by reason. Path
+// counts the files skipped by the rules (once per file) and the parser
+// findings of the allowlisted paths.
+type Suppressed struct {
+    Inline int
+    Path int
+    Regex int
+    StopWord int
+    Value int
+    Watchlist int
+}
+
+func (s Suppressed) Total() int {
+    return s.Inline + s.Path + s.Regex + s.StopWord + s.Value + s.Watchlist
+}
+
 func (st *Status) Print() { 
     if st.IsTerminal {
     	st.Spin = ascii.GetNextSpinner(st.Spin)
@@ -108,7 +141,7 @@ func (st *Status) Print() {
         }
 
     	fmt.Fprintf(os.Stderr, 
-            "%s\n %s read: %d, failed: %d, ignored: %d               \n %s cred: %d, url: %d, email: %d\r\033[A\033[A", 
+            "%s\n %s read: %d, failed: %d, ignored: %d               \n %s cred: %d, url: %d, email: %d, secret: %d, suppressed: %d\r\033[A\033[A", 
         	"                                                                        ",
         	ascii.ColoredSpin(st.Spin), 
             st.Parsed, 
@@ -117,12 +150,15 @@ func (st *Status) Print() {
             space,
             st.Credential, 
             st.Url, 
-            st.Email)
+            st.Email,
+            st.Secret,
+            st.Suppressed.Total())
     	
     }else{
         st.log.Info("STATUS", 
             "read", st.Parsed, "failed", st.Error, "ignored", st.Skipped, 
-            "creds", st.Credential, "url", st.Url, "email", st.Email)
+            "creds", st.Credential, "url", st.Url, "email", st.Email, "secret", st.Secret,
+            "suppressed", st.Suppressed.Total())
     }
 } 
 
@@ -143,7 +179,34 @@ func NewRunner(logger *slog.Logger, parser ParserDriver, opts Options, writers [
 		Rules: []*rules.Rule{},
 		Keywords: make(map[string]struct{}),
 	}
-	id.LoadRules()
+	if err := id.LoadRules(opts.Parser.RulesFile); err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := id.LoadAllowlistFile(opts.Parser.AllowlistFile); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	var watchlist *Watchlist
+	if opts.Parser.WatchlistFile != "" {
+		var err error
+		if watchlist, err = LoadWatchlist(opts.Parser.WatchlistFile); err != nil {
+			cancel()
+			return nil, err
+		}
+		logger.Info("Loaded watchlist", "file", opts.Parser.WatchlistFile, "entries", watchlist.Len())
+	}
+
+	var clients *Clients
+	if opts.Parser.ClientsFile != "" {
+		var err error
+		if clients, err = LoadClientsFile(opts.Parser.ClientsFile); err != nil {
+			cancel()
+			return nil, err
+		}
+		logger.Info("Loaded client mapping", "file", opts.Parser.ClientsFile, "clients", clients.Len())
+	}
 
 	return &Runner{
 		Parser:     parser,
@@ -156,6 +219,8 @@ func NewRunner(logger *slog.Logger, parser ParserDriver, opts Options, writers [
 		uid: 		fmt.Sprintf("%d", time.Now().UnixMilli()),
 		Identifiers: id,
 		prefilter:   *ahocorasick.NewTrieBuilder().AddStrings(maps.Keys(id.Keywords)).Build(),
+		watchlist:   watchlist,
+		clients:     clients,
 		MaxDecodeDepth: 3,
 		MaxTargetMegaBytes: 200,
 		status:     &Status{
@@ -170,7 +235,9 @@ func NewRunner(logger *slog.Logger, parser ParserDriver, opts Options, writers [
 	}, nil
 }
 
-func (id *Identifiers) LoadRules() error {
+// LoadRules loads the built-in rules and, if rulesFile is set, the
+// declarative rules from it (overriding the built-in ones by id)
+func (id *Identifiers) LoadRules(rulesFile string) error {
 	id.Rules = []*rules.Rule{
 		rules.Url(),
 		rules.Email(),
@@ -178,6 +245,22 @@ func (id *Identifiers) LoadRules() error {
         rules.Leak2(),
         rules.Leak3(),
 	}
+	id.Rules = append(id.Rules, rules.Secrets()...)
+
+	if rulesFile != "" {
+		rf, err := rules.LoadRulesFile(rulesFile)
+		if err != nil {
+			return err
+		}
+
+		if id.Rules, err = rf.Apply(id.Rules); err != nil {
+			return err
+		}
+
+		if id.Allowlist, err = rf.GlobalAllowlist(); err != nil {
+			return err
+		}
+	}
 
 	uniqueKeywords := make(map[string]struct{})
 	for _, r := range id.Rules {
@@ -194,6 +277,38 @@ func (id *Identifiers) LoadRules() error {
 	return nil
 }
 
+// LoadAllowlistFile adds the exact values (one per line) of the file to the
+// global allowlist
+func (id *Identifiers) LoadAllowlistFile(allowlistFile string) error {
+	if allowlistFile == "" {
+		return nil
+	}
+
+	if id.Allowlist == nil {
+		id.Allowlist = rules.NewAllowlist()
+	}
+	return id.Allowlist.LoadValuesFile(allowlistFile)
+}
+
+// allowlists returns the global allowlist followed by the rule ones
+func (id *Identifiers) allowlists(r *rules.Rule) []*rules.Allowlist {
+	allowlists := []*rules.Allowlist{}
+	if id.Allowlist != nil {
+		allowlists = append(allowlists, id.Allowlist)
+	}
+	if r != nil {
+		allowlists = append(allowlists, r.Allowlists...)
+	}
+	return allowlists
+}
+
+// addSuppressed counts a finding dropped by an allowlist
+func (run *Runner) addSuppressed(f func(s *Suppressed)) {
+	run.suppressedMutex.Lock()
+	defer run.suppressedMutex.Unlock()
+	f(&run.status.Suppressed)
+}
+
 // runWriters takes a result and passes it to writers
 func (run *Runner) runWriters(result *models.File) error {
 	for _, writer := range run.writers {
@@ -212,6 +327,7 @@ func (run *Runner) AddSkipped() {
 
 func (run *Runner) ParsePositionalFile(file FileItem) error {
 	_, err := run.Parser.ParseFile(run, file)
+	file.Done()
 	return err
 }
 
@@ -273,7 +389,7 @@ func (run *Runner) Run() Status {
 					if !ok || !run.status.Running {
 						return
 					}
-                    file_name := filepath.Base(file_item.RealPath)
+                    file_name := file_item.Name()
 					logger := run.log.With("file", file_name)
 					
                     logger.Debug("Indexing")
@@ -282,6 +398,7 @@ func (run *Runner) Run() Status {
                     file_item.VirtualPath = strings.Replace(file_item.VirtualPath, "\\", "/", -1)
 
 					file, err := run.Parser.ParseFile(run, file_item)
+					file_item.Done()
 					if err != nil {
 						file.Failed = true
 						file.FailedReason = err.Error()
@@ -292,6 +409,7 @@ func (run *Runner) Run() Status {
 
                     if run.status.Running {
                         if file != nil {
+                            run.applyFileWatchlist(file)
         					run.status.AddResult(file)
 
         					if err := run.runWriters(file); err != nil {
@@ -367,6 +485,10 @@ func (run *Runner) Detect(fragment Fragment) []models.Finding {
     // add newline indices for location calculation in detectRule
     fragment.newlineIndices = newLineRegexp.FindAllStringIndex(fragment.Raw, -1)
 
+    if fragment.pathAllowed == nil {
+        fragment.pathAllowed = run.pathAllowlisted(fragment.FilePath)
+    }
+
     // setup variables to handle different decoding passes
     currentRaw := fragment.Raw
     encodedSegments := []EncodedSegment{}
@@ -383,6 +505,10 @@ func (run *Runner) Detect(fragment Fragment) []models.Finding {
         }
 
         for _, rule := range run.Identifiers.Rules {
+            if fragment.pathAllowed[rule] {
+                continue
+            }
+
             if len(rule.Keywords) == 0 {
                 // if no keywords are associated with the rule always scan the
                 // fragment using the rule
@@ -419,11 +545,15 @@ func (run *Runner) Detect(fragment Fragment) []models.Finding {
     return findings
 }
 
-// DetectReader accepts an io.Reader and a buffer size for the reader in KB
+// DetectReader accepts an io.Reader and a buffer size for the reader in KB.
+// The findings have their lines and offsets relative to the whole content,
+// and are not added to any file (see AddFinding).
 func (run *Runner) DetectReader(r io.Reader, bufSize int) ([]models.Finding, error) {
     reader := bufio.NewReader(r)
     buf := make([]byte, 1000*bufSize)
     findings := []models.Finding{}
+    totalLines := 0
+    totalBytes := 0
 
     for {
         n, err := reader.Read(buf)
@@ -437,10 +567,20 @@ func (run *Runner) DetectReader(r io.Reader, bufSize int) ([]models.Finding, err
                 return findings, readErr
             }
 
+            chunk := peekBuf.String()
+            linesInChunk := strings.Count(chunk, "\n")
+            totalLines += linesInChunk
+            chunkOffset := totalBytes
+            totalBytes += len(chunk)
             fragment := Fragment{
-                Raw: peekBuf.String(),
+                Raw:   chunk,
+                Bytes: peekBuf.Bytes(),
             }
             for _, finding := range run.Detect(fragment) {
+                // need to add 1 since line counting starts at 1
+                finding.StartLine += (totalLines - linesInChunk) + 1
+                finding.EndLine += (totalLines - linesInChunk) + 1
+                finding.Offset += chunkOffset
                 findings = append(findings, finding)
             }
         }
@@ -456,11 +596,42 @@ func (run *Runner) DetectReader(r io.Reader, bufSize int) ([]models.Finding, err
     return findings, nil
 }
 
+// DetectFile scans the file at file.FilePath
 func (run *Runner) DetectFile(file *models.File) error {
+    return run.DetectFileItem(file, FileItem{RealPath: file.FilePath})
+}
+
+// DetectFileItem scans the content of the item (on disk or streamed, e.g.
+// from a ZIP entry) and adds the findings to file
+func (run *Runner) DetectFileItem(file *models.File, item FileItem) error {
+    return run.detectFileItem(file, item, run.AddFinding)
+}
+
+// DetectSecrets scans the content of the item like DetectFileItem, adding
+// only the findings with a secret (API tokens, keys and connection strings).
+// Parsers that map the credentials by themselves (e.g. the structured
+// files) use it, so the secrets in the other columns are not lost.
+func (run *Runner) DetectSecrets(file *models.File, item FileItem) error {
+    return run.detectFileItem(file, item, func(file *models.File, finding models.Finding) {
+        if finding.SecretEntity.Value != "" {
+            run.AddFinding(file, finding)
+        }
+    })
+}
+
+// detectFileItem scans the content of the item, passing the findings to add
+func (run *Runner) detectFileItem(file *models.File, item FileItem, add func(*models.File, models.Finding)) error {
     logger := run.log.With("path", file.FilePath)
     logger.Debug("Scanning path")
 
-    f, err := os.Open(file.FilePath)
+    // The path allowlists are checked (and counted) once per file
+    pathAllowed := run.pathAllowlisted(file.FilePath)
+    if len(pathAllowed) > 0 && len(pathAllowed) == len(run.Identifiers.Rules) {
+        logger.Debug("Skipping file: allowlist path")
+        return nil
+    }
+
+    f, err := item.Open()
     if err != nil {
         if os.IsPermission(err) {
             logger.Warn("Skipping file: permission denied")
@@ -473,11 +644,10 @@ func (run *Runner) DetectFile(file *models.File) error {
     }()
 
     // Get file size
-    fileInfo, err := f.Stat()
+    fileSize, err := item.Size()
     if err != nil {
         return err
     }
-    fileSize := fileInfo.Size()
     if run.MaxTargetMegaBytes > 0 {
         rawLength := fileSize / 1000000
         if rawLength > int64(run.MaxTargetMegaBytes) {
@@ -491,6 +661,7 @@ func (run *Runner) DetectFile(file *models.File) error {
         reader     = bufio.NewReaderSize(f, chunkSize)
         buf        = make([]byte, chunkSize)
         totalLines = 0
+        totalBytes = 0
         resultMutex sync.Mutex
     )
     for {
@@ -501,6 +672,11 @@ func (run *Runner) DetectFile(file *models.File) error {
         if n > 0 {
             // Only check the filetype at the start of file.
             if totalLines == 0 {
+                // Office and PDF documents have their text extracted
+                if format := extract.Detect(item.Name(), buf[:n]); format != extract.FormatUnknown {
+                    return run.detectDocument(file, item, format, pathAllowed, add)
+                }
+
                 // TODO: could other optimizations be introduced here?
                 if mimetype, err := filetype.Match(buf[:n]); err != nil {
                     return err
@@ -519,10 +695,13 @@ func (run *Runner) DetectFile(file *models.File) error {
             chunk := peekBuf.String()
             linesInChunk := strings.Count(chunk, "\n")
             totalLines += linesInChunk
+            chunkOffset := totalBytes
+            totalBytes += len(chunk)
             fragment := Fragment{
-                Raw:      chunk,
-                Bytes:    peekBuf.Bytes(),
-                FilePath: file.FilePath,
+                Raw:         chunk,
+                Bytes:       peekBuf.Bytes(),
+                FilePath:    file.FilePath,
+                pathAllowed: pathAllowed,
             }
             for _, finding := range run.Detect(fragment) {
                 if !run.status.Running {
@@ -532,27 +711,9 @@ func (run *Runner) DetectFile(file *models.File) error {
                 // need to add 1 since line counting starts at 1
                 finding.StartLine += (totalLines - linesInChunk) + 1
                 finding.EndLine += (totalLines - linesInChunk) + 1
+                finding.Offset += chunkOffset
                 resultMutex.Lock()
-
-                if finding.Credential.Username != "" {
-                    run.status.Credential += 1
-                    finding.Credential.Time = file.Date
-                    finding.Credential.Rule = finding.RuleID
-                    file.Credentials = append(file.Credentials, finding.Credential)
-                }
-
-                if finding.Email.Email != "" {
-                    run.status.Email += 1
-                    finding.Email.Time = file.Date
-                    file.Emails = append(file.Emails, finding.Email)
-                }
-
-                if finding.Url.Url != "" {
-                    run.status.Url += 1
-                    finding.Url.Time = file.Date
-                    file.URLs = append(file.URLs, finding.Url)
-                }
-
+                add(file, finding)
                 resultMutex.Unlock()
 
             }
@@ -569,6 +730,160 @@ func (run *Runner) DetectFile(file *models.File) error {
     return nil
 }
 
+// AddFinding attaches the Credential, Email, URL and Secret carried by the finding
+// to the file and updates the execution status. The findings of the parsers
+// that extract entities by themselves go through AddParsedFinding.
+func (run *Runner) AddFinding(file *models.File, finding models.Finding) {
+    run.applyWatchlist(&finding)
+
+    if finding.Credential.Username != "" {
+        run.status.Credential += 1
+        finding.Credential.Time = file.Date
+        finding.Credential.Rule = finding.RuleID
+        finding.Credential.StartLine = finding.StartLine
+        finding.Credential.EndLine = finding.EndLine
+        finding.Credential.StartColumn = finding.StartColumn
+        finding.Credential.EndColumn = finding.EndColumn
+        finding.Credential.Offset = finding.Offset
+        finding.Credential.Location = finding.Location
+        finding.Credential.UserRegistrableDomain = tools.RegistrableDomain(finding.Credential.UserDomain)
+        finding.Credential.UrlRegistrableDomain = tools.RegistrableDomain(finding.Credential.UrlDomain)
+        file.Credentials = append(file.Credentials, finding.Credential)
+    }
+
+    if finding.Email.Email != "" {
+        run.status.Email += 1
+        finding.Email.Time = file.Date
+        finding.Email.StartLine = finding.StartLine
+        finding.Email.EndLine = finding.EndLine
+        finding.Email.StartColumn = finding.StartColumn
+        finding.Email.EndColumn = finding.EndColumn
+        finding.Email.Offset = finding.Offset
+        finding.Email.Location = finding.Location
+        finding.Email.RegistrableDomain = tools.RegistrableDomain(finding.Email.Domain)
+        file.Emails = append(file.Emails, finding.Email)
+    }
+
+    if finding.Url.Url != "" {
+        run.status.Url += 1
+        finding.Url.Time = file.Date
+        finding.Url.StartLine = finding.StartLine
+        finding.Url.EndLine = finding.EndLine
+        finding.Url.StartColumn = finding.StartColumn
+        finding.Url.EndColumn = finding.EndColumn
+        finding.Url.Offset = finding.Offset
+        finding.Url.Location = finding.Location
+        finding.Url.RegistrableDomain = tools.RegistrableDomain(finding.Url.Domain)
+        file.URLs = append(file.URLs, finding.Url)
+    }
+
+    if finding.SecretEntity.Value != "" {
+        run.status.Secret += 1
+        finding.SecretEntity.Time = file.Date
+        finding.SecretEntity.Rule = finding.RuleID
+        finding.SecretEntity.StartLine = finding.StartLine
+        finding.SecretEntity.EndLine = finding.EndLine
+        finding.SecretEntity.StartColumn = finding.StartColumn
+        finding.SecretEntity.EndColumn = finding.EndColumn
+        finding.SecretEntity.Offset = finding.Offset
+        finding.SecretEntity.Location = finding.Location
+        file.Secrets = append(file.Secrets, finding.SecretEntity)
+    }
+}
+
+// AddParsedFinding adds a finding built by a parser (with no regex rule,
+// e.g. the stealer logs or the SQL dumps) to the file and tags its client.
+// The finding is checked against the global allowlist first: its File (the
+// file path, by default) and Line are matched by the path and inline
+// allow rules. Returns false if an allowlist dropped it.
+func (run *Runner) AddParsedFinding(file *models.File, finding models.Finding) bool {
+    if finding.File == "" {
+        finding.File = file.FilePath
+    }
+
+    allowlists := run.Identifiers.allowlists(nil)
+    for _, a := range allowlists {
+        if a.PathAllowed(finding.File) {
+            run.log.Debug("skipping finding: allowlist path", "rule", finding.RuleID, "path", finding.File)
+            run.addSuppressed(func(s *Suppressed) { s.Path++ })
+            return false
+        }
+    }
+
+    if run.inlineAllowed(&finding) || run.allowlistAllowed(allowlists, &finding) || run.valueAllowed(allowlists, &finding) {
+        return false
+    }
+
+    run.TagClient(&finding)
+    run.AddFinding(file, finding)
+    return true
+}
+
+// applyFileWatchlist drops the saved content of the file (e.g. the IntelX
+// content or the stealer autofill) when none of its entities matched the
+// watchlist. The secrets were already filtered by applyWatchlist.
+func (run *Runner) applyFileWatchlist(file *models.File) {
+    if run.watchlist == nil {
+        return
+    }
+
+    if len(file.Credentials) + len(file.Emails) + len(file.URLs) + len(file.Secrets) == 0 {
+        file.Content = ""
+    }
+}
+
+// applyWatchlist drops the entities of the finding outside the watchlist,
+// so they never reach the writers (the file itself is still written, so
+// the control database knows it was parsed)
+func (run *Runner) applyWatchlist(finding *models.Finding) {
+    if run.watchlist == nil {
+        return
+    }
+
+    dropped := 0
+    if finding.Credential.Username != "" && !run.watchlist.MatchCredential(finding.Credential) {
+        finding.Credential.Username = ""
+        dropped++
+    }
+    if finding.Email.Email != "" && !run.watchlist.MatchEmail(finding.Email) {
+        finding.Email.Email = ""
+        dropped++
+    }
+    if finding.Url.Url != "" && !run.watchlist.MatchURL(finding.Url) {
+        finding.Url.Url = ""
+        dropped++
+    }
+    if finding.SecretEntity.Value != "" && !run.watchlist.MatchSecret(finding.SecretEntity) {
+        finding.SecretEntity.Value = ""
+        dropped++
+    }
+
+    if dropped > 0 {
+        run.addSuppressed(func(s *Suppressed) { s.Watchlist += dropped })
+    }
+}
+
+// TagClient sets the client of the credential, e-mail and URL of the
+// finding, by the client mapping file
+func (run *Runner) TagClient(finding *models.Finding) {
+    if run.clients == nil {
+        return
+    }
+
+    if finding.Credential.Username != "" {
+        finding.Credential.Client = run.clients.TagCredential(finding.Credential)
+    }
+    if finding.Email.Email != "" {
+        finding.Email.Client = run.clients.TagEmail(finding.Email)
+    }
+    if finding.Url.Url != "" {
+        finding.Url.Client = run.clients.TagURL(finding.Url)
+    }
+    if finding.SecretEntity.Value != "" {
+        finding.SecretEntity.Client = run.clients.TagSecret(finding.SecretEntity)
+    }
+}
+
 func isWhitespace(ch byte) bool {
 	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
@@ -597,6 +912,12 @@ func shannonEntropy(data string) (entropy float64) {
 	return entropy
 }
 
+// ShannonEntropy is the exported form of shannonEntropy, used by the parser
+// drivers to score the secrets they extract by themselves.
+func ShannonEntropy(data string) float32 {
+	return float32(shannonEntropy(data))
+}
+
 // readUntilSafeBoundary consumes |f| until it finds two consecutive `\n` characters, up to |maxPeekSize|.
 // This hopefully avoids splitting. (https://github.com/gitleaks/gitleaks/issues/1651)
 func readUntilSafeBoundary(r *bufio.Reader, n int, maxPeekSize int, peekBuf *bytes.Buffer) error {
@@ -677,10 +998,11 @@ func ContainsStopWord(s string) (bool, string) {
 	return false, ""
 }
 
+// ContainsEmailDomainStopWord tells if the domain is one of the e-mail
+// domain stopwords or one of their subdomains
 func ContainsEmailDomainStopWord(s string) (bool, string) {
-    s = strings.ToLower(s)
     for _, stopWord := range rules.EmailDomainStopWords {
-        if strings.Contains(s, strings.ToLower(stopWord)) {
+        if tools.IsDomainOrSubdomain(s, stopWord) {
             return true, stopWord
         }
     }
@@ -688,10 +1010,12 @@ func ContainsEmailDomainStopWord(s string) (bool, string) {
 }
 
 
+// ContainsUrlDomainStopWord tells if the domain is one of the URL domain
+// stopwords or one of their subdomains (e.g. x.com drops mail.x.com, but
+// not box.com)
 func ContainsUrlDomainStopWord(s string) (bool, string) {
-    s = strings.ToLower(s)
     for _, stopWord := range rules.UrlDomainStopWords {
-        if strings.Contains(s, strings.ToLower(stopWord)) {
+        if tools.IsDomainOrSubdomain(s, stopWord) {
             return true, stopWord
         }
     }
@@ -699,11 +1023,53 @@ func ContainsUrlDomainStopWord(s string) (bool, string) {
 }
 
 
+// rawReplacements are the encoded chars (URL encoding and HTML line
+// breaks) replaced before the rules run, in order of precedence
+var rawReplacements = [][2]string{
+	{"%40", "@"}, {"%20", " "}, {"%22", "\""}, {"%27", "'"},
+	{"%7b", "{"}, {"%7d", "}"}, {"%5b", "["},
+	{"%0a", "\n"}, {"%0d", "\r"}, {"%09", "\t"},
+	{"<br />", "\n"}, {"<br/>", "\n"}, {"<br>", "\n"},
+}
+
+// unescapeRaw replaces the rawReplacements of raw, returning the new text
+// and, for each of its positions (and its end), the position in raw
+func unescapeRaw(raw string) (string, []int) {
+	var b strings.Builder
+	index := make([]int, 0, len(raw)+1)
+
+	for i := 0; i < len(raw); {
+		replaced := false
+		if raw[i] == '%' || raw[i] == '<' {
+			for _, r := range rawReplacements {
+				if strings.HasPrefix(raw[i:], r[0]) {
+					for j := 0; j < len(r[1]); j++ {
+						index = append(index, i)
+					}
+					b.WriteString(r[1])
+					i += len(r[0])
+					replaced = true
+					break
+				}
+			}
+		}
+		if !replaced {
+			index = append(index, i)
+			b.WriteByte(raw[i])
+			i++
+		}
+	}
+	index = append(index, len(raw))
+
+	return b.String(), index
+}
+
 // detectRule scans the given fragment for the given rule and returns a list of findings
 func (run *Runner) detectRule(fragment Fragment, currentRaw string, r *rules.Rule, encodedSegments []EncodedSegment) []models.Finding {
 	var (
-		findings []models.Finding
-		logger   = run.log.With("rule", r.RuleID)
+		findings   []models.Finding
+		logger     = run.log.With("rule", r.RuleID)
+		allowlists = run.Identifiers.allowlists(r)
 	)
 
     if !run.status.Running {
@@ -749,20 +1115,9 @@ func (run *Runner) detectRule(fragment Fragment, currentRaw string, r *rules.Rul
 		}
 	}
 
-    // Replace the main encoding chars
-    currentRaw = strings.Replace(currentRaw, "%40", "@", -1)
-    currentRaw = strings.Replace(currentRaw, "%20", " ", -1)
-    currentRaw = strings.Replace(currentRaw, "%22", "\"", -1)
-    currentRaw = strings.Replace(currentRaw, "%27", "'", -1)
-    currentRaw = strings.Replace(currentRaw, "%7b", "{", -1)
-    currentRaw = strings.Replace(currentRaw, "%7d", "}", -1)
-    currentRaw = strings.Replace(currentRaw, "%5b", "[", -1)
-    currentRaw = strings.Replace(currentRaw, "%0a", "\n", -1)
-    currentRaw = strings.Replace(currentRaw, "%0d", "\r", -1)
-    currentRaw = strings.Replace(currentRaw, "%09", "\t", -1)
-    currentRaw = strings.Replace(currentRaw, "<br />", "\n", -1)
-    currentRaw = strings.Replace(currentRaw, "<br/>", "\n", -1)
-    currentRaw = strings.Replace(currentRaw, "<br>", "\n", -1)
+    // Replace the main encoding chars. rawIndex maps the positions back to
+    // the text before the replacements (offsets, lines and columns)
+    currentRaw, rawIndex := unescapeRaw(currentRaw)
 
     
 	// use currentRaw instead of fragment.Raw since this represents the current
@@ -779,6 +1134,7 @@ func (run *Runner) detectRule(fragment Fragment, currentRaw string, r *rules.Rul
 		// Check if the decoded portions of the segment overlap with the match
 		// to see if its potentially a new match
 		if len(encodedSegments) > 0 {
+			matchIndex = []int{rawIndex[matchIndex[0]], rawIndex[matchIndex[1]]}
 			if segment := segmentWithDecodedOverlap(encodedSegments, matchIndex[0], matchIndex[1]); segment != nil {
 				matchIndex = segment.adjustMatchIndex(matchIndex)
 				metaTags = append(metaTags, segment.tags()...)
@@ -789,7 +1145,7 @@ func (run *Runner) detectRule(fragment Fragment, currentRaw string, r *rules.Rul
 		} else {
 			// Fixes: https://github.com/gitleaks/gitleaks/issues/1352
 			// removes the incorrectly following line that was detected by regex expression '\n'
-			matchIndex[1] = matchIndex[0] + len(secret)
+			matchIndex = []int{rawIndex[matchIndex[0]], rawIndex[matchIndex[0]+len(secret)]}
 		}
 
         //Recheck RegExp Match
@@ -818,6 +1174,7 @@ func (run *Runner) detectRule(fragment Fragment, currentRaw string, r *rules.Rul
 			EndColumn:   loc.endColumn,
 			Secret:      secret,
 			Match:       secret,
+			Offset:      matchIndex[0],
 			Tags:        append(r.Tags, metaTags...),
 			Line:        fragment.Raw[loc.startLineIndex:loc.endLineIndex],
 		}
@@ -845,9 +1202,11 @@ func (run *Runner) detectRule(fragment Fragment, currentRaw string, r *rules.Rul
 
         if len(r.Keywords) > 0 {
             ok := false
-            // check if keywords are in the Math
+            // check if keywords are in the Match (case insensitive, like
+            // the prefilter)
+            match := strings.ToLower(finding.Match)
             for _, k := range r.Keywords {
-                if kok := strings.Contains(finding.Match, k); kok {
+                if kok := strings.Contains(match, strings.ToLower(k)); kok {
                     ok = true
                 }
             }
@@ -858,6 +1217,10 @@ func (run *Runner) detectRule(fragment Fragment, currentRaw string, r *rules.Rul
             }
         }
 
+		if run.inlineAllowed(&finding) {
+			continue
+		}
+
 		// check entropy
 		entropy := shannonEntropy(finding.Secret)
 		finding.Entropy = float32(entropy)
@@ -872,10 +1235,15 @@ func (run *Runner) detectRule(fragment Fragment, currentRaw string, r *rules.Rul
 		if r.CheckGlobalStopWord {
 			if ok, word := ContainsStopWord(finding.Secret); ok {
 				logger.Debug("skipping finding: global allowlist stopword", "match", finding.Match, "secret", finding.Secret, "allowed-stopword", word)
+				run.addSuppressed(func(s *Suppressed) { s.StopWord++ })
 				continue
 			}
 		}
 
+		if run.allowlistAllowed(allowlists, &finding) {
+			continue
+		}
+
         //Process final finding
         ok, err := r.PostProcessor(&finding); 
         if err != nil {
@@ -886,6 +1254,10 @@ func (run *Runner) detectRule(fragment Fragment, currentRaw string, r *rules.Rul
             continue
         }
 
+        if run.valueAllowed(allowlists, &finding) {
+            continue
+        }
+
 
         nearText := ""
         if run.options.Parser.StoreNearText {
@@ -923,6 +1295,10 @@ func (run *Runner) detectRule(fragment Fragment, currentRaw string, r *rules.Rul
             }
         }
 
+        if finding.SecretEntity.Value != "" {
+            finding.SecretEntity.NearText = nearText
+        }
+
         if finding.Url.Url != "" {
             finding.Url.NearText = nearText
             if ok, _ := ContainsUrlDomainStopWord(finding.Url.Domain); ok { 
@@ -930,15 +1306,98 @@ func (run *Runner) detectRule(fragment Fragment, currentRaw string, r *rules.Rul
             }
         }
 
-        if finding.Credential.Username == "" && finding.Email.Email == "" && finding.Url.Url == "" {
+        if finding.Credential.Username == "" && finding.Email.Email == "" && finding.Url.Url == "" && finding.SecretEntity.Value == "" {
+            // All entities were dropped by the domain stopwords
+            run.addSuppressed(func(s *Suppressed) { s.StopWord++ })
             continue
         }
 
+        run.TagClient(&finding)
+
 		findings = append(findings, finding)
 	}
 	return findings
 }
 
+// pathAllowlisted returns the rules whose allowlists (the global one
+// included) match the path, counting the path once if any of them does
+func (run *Runner) pathAllowlisted(path string) map[*rules.Rule]bool {
+	allowed := map[*rules.Rule]bool{}
+	if path == "" {
+		return allowed
+	}
+
+	for _, r := range run.Identifiers.Rules {
+		for _, a := range run.Identifiers.allowlists(r) {
+			if a.PathAllowed(path) {
+				allowed[r] = true
+				break
+			}
+		}
+	}
+	if len(allowed) > 0 {
+		run.log.Debug("skipping rules: allowlist path", "path", path, "rules", len(allowed))
+		run.addSuppressed(func(s *Suppressed) { s.Path++ })
+	}
+	return allowed
+}
+
+// inlineAllowed tells if the line of the finding has the inline allow
+// signature, counting it
+func (run *Runner) inlineAllowed(finding *models.Finding) bool {
+	if !strings.Contains(finding.Line, gitleaksAllowSignature) {
+		return false
+	}
+	run.log.Debug("skipping finding: inline allow signature", "rule", finding.RuleID, "match", finding.Match)
+	run.addSuppressed(func(s *Suppressed) { s.Inline++ })
+	return true
+}
+
+// allowlistAllowed tells if the finding matches the regexes or the
+// stopwords of the allowlists, counting it
+func (run *Runner) allowlistAllowed(allowlists []*rules.Allowlist, finding *models.Finding) bool {
+	allowed, reason := allowlistedFinding(allowlists, finding)
+	if !allowed {
+		return false
+	}
+	run.log.Debug("skipping finding: allowlist " + reason, "rule", finding.RuleID, "match", finding.Match, "secret", finding.Secret)
+	run.addSuppressed(func(s *Suppressed) {
+		if reason == "regex" {
+			s.Regex++
+		}else{
+			s.StopWord++
+		}
+	})
+	return true
+}
+
+// valueAllowed tells if the secret or an entity of the finding is one of
+// the allowlists exact values, counting it
+func (run *Runner) valueAllowed(allowlists []*rules.Allowlist, finding *models.Finding) bool {
+	for _, a := range allowlists {
+		if a.ValueAllowed(finding) {
+			run.log.Debug("skipping finding: allowlist value", "rule", finding.RuleID, "match", finding.Match, "secret", finding.Secret)
+			run.addSuppressed(func(s *Suppressed) { s.Value++ })
+			return true
+		}
+	}
+	return false
+}
+
+// allowlistedFinding checks the allowlists regexes and stopwords against the
+// finding, returning the reason ("regex" or "stopword") if allowed
+func allowlistedFinding(allowlists []*rules.Allowlist, finding *models.Finding) (bool, string) {
+	for _, a := range allowlists {
+		if a.RegexAllowed(finding) {
+			return true, "regex"
+		}
+		if ok, _ := a.ContainsStopWord(finding.Secret); ok {
+			return true, "stopword"
+		}
+	}
+	return false, ""
+}
+
 func allTrue(bools []bool) bool {
 	allMatch := true
 	for _, check := range bools {
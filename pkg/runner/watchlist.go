@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"strings"
+
+	ahocorasick "github.com/BobuSumisu/aho-corasick"
+	"github.com/helviojunior/intelparser/pkg/models"
+	"github.com/helviojunior/intelparser/pkg/readers"
+)
+
+// Watchlist entry kinds
+const (
+	watchDomain = iota
+	watchEmail
+	watchKeyword
+)
+
+// Watchlist keeps only the findings of the watched domains, e-mails and
+// keywords (e.g. the client domains). The entries are compiled into an
+// Aho-Corasick trie, so each value is checked in a single pass.
+type Watchlist struct {
+	entries []string
+	kinds   []int
+	seen    map[string]bool
+	trie    *ahocorasick.Trie
+}
+
+// NewWatchlist compiles the entries: a domain (client.com) matches itself
+// and its subdomains, an e-mail (user@client.com) the whole address and
+// any other entry is a keyword, matched anywhere
+func NewWatchlist(entries []string) *Watchlist {
+	w := &Watchlist{seen: map[string]bool{}}
+	for _, e := range entries {
+		kind := watchKeyword
+		if strings.Contains(e, "@") {
+			kind = watchEmail
+		} else if strings.Contains(e, ".") && !strings.ContainsAny(strings.TrimSpace(e), " /:") {
+			kind = watchDomain
+		}
+		w.add(e, kind)
+	}
+	return w.build()
+}
+
+// add appends an entry of the kind, ignoring comments and duplicates
+func (w *Watchlist) add(entry string, kind int) {
+	entry = strings.ToLower(strings.Trim(entry, " .\r\n\t"))
+	if entry == "" || strings.HasPrefix(entry, "#") || w.seen[entry] {
+		return
+	}
+	w.seen[entry] = true
+	w.entries = append(w.entries, entry)
+	w.kinds = append(w.kinds, kind)
+}
+
+// build compiles the entries into the trie
+func (w *Watchlist) build() *Watchlist {
+	w.trie = ahocorasick.NewTrieBuilder().AddStrings(w.entries).Build()
+	return w
+}
+
+// LoadWatchlist reads the watchlist entries from the file, one per line
+// (the lines starting with # are comments)
+func LoadWatchlist(file_path string) (*Watchlist, error) {
+	entries := []string{}
+	if err := readers.ReadFileList(file_path, &entries); err != nil {
+		return nil, err
+	}
+	return NewWatchlist(entries), nil
+}
+
+// Len returns the number of entries
+func (w *Watchlist) Len() int {
+	return len(w.entries)
+}
+
+// Match tells if any of the values has a watched entry
+func (w *Watchlist) Match(values ...string) bool {
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+
+		found := false
+		data := []byte(strings.ToLower(v))
+		w.trie.Walk(data, func(end, n, pattern int64) bool {
+			start := int(end - n + 1)
+			switch w.kinds[pattern] {
+			case watchDomain:
+				// the domain or a subdomain: client.com matches
+				// www.client.com and user@client.com, not myclient.com
+				// or client.com.br
+				found = !isHostChar(data, start-1, false) && !isHostChar(data, int(end)+1, true)
+			case watchEmail:
+				found = !isEmailChar(data, start-1) && !isHostChar(data, int(end)+1, true)
+			default:
+				found = true
+			}
+			return !found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchCredential tells if the credential is of a watched user, domain or
+// URL
+func (w *Watchlist) MatchCredential(c models.Credential) bool {
+	return w.Match(c.Username, c.UserDomain, c.Url, c.UrlDomain)
+}
+
+// MatchEmail tells if the e-mail is watched
+func (w *Watchlist) MatchEmail(e models.Email) bool {
+	return w.Match(e.Email)
+}
+
+// MatchURL tells if the URL is of a watched domain or has a keyword
+func (w *Watchlist) MatchURL(u models.URL) bool {
+	return w.Match(u.Url)
+}
+
+// MatchSecret tells if the secret value has a watched entry
+func (w *Watchlist) MatchSecret(s models.Secret) bool {
+	return w.Match(s.Value)
+}
+
+// isHostChar tells if the byte at i continues a host name. After the
+// match, a dot continues it too (client.com.br is not client.com).
+func isHostChar(data []byte, i int, after bool) bool {
+	if i < 0 || i >= len(data) {
+		return false
+	}
+	c := data[i]
+	return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || (after && c == '.')
+}
+
+// isEmailChar tells if the byte at i is part of the local part of an
+// e-mail address
+func isEmailChar(data []byte, i int) bool {
+	if i < 0 || i >= len(data) {
+		return false
+	}
+	return isHostChar(data, i, true) || strings.IndexByte("_%+", data[i]) >= 0
+}
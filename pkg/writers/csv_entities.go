@@ -0,0 +1,114 @@
+package writers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/models"
+)
+
+var csvEntityFiles = map[string]string{
+	EntityCreds:   "credentials",
+	EntityEmails:  "emails",
+	EntityUrls:    "urls",
+	EntitySecrets: "secrets",
+}
+
+// CsvEntityWriter writes one CSV file per entity type (credentials, e-mails,
+// URLs and secrets) next to the destination, e.g. intelparser.csv becomes
+// intelparser_credentials.csv, intelparser_emails.csv and so on. The files
+// are created with the first row, so empty entities have no file.
+type CsvEntityWriter struct {
+	FilePath string
+	basePath string
+
+	created map[string]bool
+	mutex   sync.Mutex
+}
+
+// NewCsvEntityWriter gets a new CsvEntityWriter
+func NewCsvEntityWriter(destination string) (*CsvEntityWriter, error) {
+	dir, err := tools.CreateDir(filepath.Dir(destination))
+	if err != nil {
+		return nil, err
+	}
+
+	file := filepath.Base(destination)
+	return &CsvEntityWriter{
+		FilePath: destination,
+		basePath: filepath.Join(dir, strings.TrimSuffix(file, filepath.Ext(file))),
+		created:  map[string]bool{},
+	}, nil
+}
+
+// EntityFilePath returns the CSV file path of the entity
+func (cw *CsvEntityWriter) EntityFilePath(entity string) string {
+	return cw.basePath + "_" + csvEntityFiles[entity] + ".csv"
+}
+
+// Write the entity rows of the file
+func (cw *CsvEntityWriter) Write(result *models.File) error {
+	rows := map[string][][]string{}
+	for entity, values := range entityRows(result) {
+		for _, v := range values {
+			rows[entity] = append(rows[entity], csvRow(v))
+		}
+	}
+
+	cw.mutex.Lock()
+	defer cw.mutex.Unlock()
+
+	for _, entity := range StdoutEntities {
+		if len(rows[entity]) == 0 {
+			continue
+		}
+		if err := cw.writeRows(entity, rows[entity]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cw *CsvEntityWriter) writeRows(entity string, rows [][]string) error {
+	flags := os.O_WRONLY | os.O_APPEND | os.O_CREATE
+	if !cw.created[entity] {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(cw.EntityFilePath(entity), flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if !cw.created[entity] {
+		cw.created[entity] = true
+		if err := writer.Write(entityColumns(entity)); err != nil {
+			return err
+		}
+	}
+
+	return writer.WriteAll(rows)
+}
+
+func csvRow(values []interface{}) []string {
+	row := make([]string, len(values))
+	for i, v := range values {
+		if t, ok := v.(time.Time); ok {
+			row[i] = t.Format(time.RFC3339)
+			continue
+		}
+		row[i] = fmt.Sprintf("%v", v)
+	}
+	return row
+}
@@ -310,6 +310,8 @@ func NewElasticWriter(uri string, debug bool) (*ElasticWriter, error) {
                     "provider_id": {"type": "text"},
                     "bucket": {"type": "text"},
                     "media_type": {"type": "text"},
+                    "mail_from": {"type": "text"},
+                    "mail_subject": {"type": "text"},
                     "content": {"type": "text"}
                 }
             }
@@ -331,13 +333,22 @@ func NewElasticWriter(uri string, debug bool) (*ElasticWriter, error) {
                     "fingerprint": {"type": "keyword"},
                     "rule": {"type": "keyword"},
                     "user_domain": {"type": "keyword"},
+                    "user_registrable_domain": {"type": "keyword"},
                     "username": {"type": "keyword"},
                     "password": {"type": "keyword"},
                     "cpf": {"type": "keyword"},
                     "url": {"type": "keyword"},
                     "url_domain": {"type": "keyword"},
+                    "url_registrable_domain": {"type": "keyword"},
+                    "client": {"type": "keyword"},
                     "severity": {"type": "long"},
                     "entropy": {"type": "long"},
+                    "start_line": {"type": "long"},
+                    "end_line": {"type": "long"},
+                    "start_column": {"type": "long"},
+                    "end_column": {"type": "long"},
+                    "offset": {"type": "long"},
+                    "location": {"type": "keyword"},
                     "near_text": {"type": "text"},
                     "bucket": {"type": "text"},
                     "file_id": {"type": "keyword"}
@@ -360,7 +371,15 @@ func NewElasticWriter(uri string, debug bool) (*ElasticWriter, error) {
                     "time": {"type": "date"},
                     "fingerprint": {"type": "keyword"},
                     "domain": {"type": "keyword"},
+                    "registrable_domain": {"type": "keyword"},
+                    "client": {"type": "keyword"},
                     "url": {"type": "keyword"},
+                    "start_line": {"type": "long"},
+                    "end_line": {"type": "long"},
+                    "start_column": {"type": "long"},
+                    "end_column": {"type": "long"},
+                    "offset": {"type": "long"},
+                    "location": {"type": "keyword"},
                     "near_text": {"type": "text"},
                     "bucket": {"type": "text"},
                     "file_id": {"type": "keyword"}
@@ -384,7 +403,47 @@ func NewElasticWriter(uri string, debug bool) (*ElasticWriter, error) {
                     "time": {"type": "date"},
                     "fingerprint": {"type": "keyword"},
                     "domain": {"type": "keyword"},
+                    "registrable_domain": {"type": "keyword"},
+                    "client": {"type": "keyword"},
                     "email": {"type": "keyword"},
+                    "start_line": {"type": "long"},
+                    "end_line": {"type": "long"},
+                    "start_column": {"type": "long"},
+                    "end_column": {"type": "long"},
+                    "offset": {"type": "long"},
+                    "location": {"type": "keyword"},
+                    "near_text": {"type": "text"},
+                    "bucket": {"type": "text"},
+                    "file_id": {"type": "keyword"}
+                }
+            }
+		}`)
+	if err != nil {
+	    return nil, err
+	}
+
+	//Secrets Index
+	err = wr.CreateIndex(wr.Index + "_secrets", `{
+		    "settings": {
+                    "number_of_replicas": 1,
+                    "index": {"highlight.max_analyzed_offset": 10000000}
+                },
+
+            "mappings": {
+                "properties": {
+                    "time": {"type": "date"},
+                    "fingerprint": {"type": "keyword"},
+                    "rule": {"type": "keyword"},
+                    "type": {"type": "keyword"},
+                    "value": {"type": "keyword"},
+                    "entropy": {"type": "float"},
+                    "client": {"type": "keyword"},
+                    "start_line": {"type": "long"},
+                    "end_line": {"type": "long"},
+                    "start_column": {"type": "long"},
+                    "end_column": {"type": "long"},
+                    "offset": {"type": "long"},
+                    "location": {"type": "keyword"},
                     "near_text": {"type": "text"},
                     "bucket": {"type": "text"},
                     "file_id": {"type": "keyword"}
@@ -396,7 +455,7 @@ func NewElasticWriter(uri string, debug bool) (*ElasticWriter, error) {
 	}
 
 	// Apply ingest-friendly settings to all managed indices (new and existing).
-	for _, idx := range []string{wr.Index, wr.Index + "_creds", wr.Index + "_urls", wr.Index + "_emails"} {
+	for _, idx := range []string{wr.Index, wr.Index + "_creds", wr.Index + "_urls", wr.Index + "_emails", wr.Index + "_secrets"} {
 		if err := wr.applyIngestSettings(idx); err != nil {
 			logger.Warnf("Could not apply ingest settings to %s: %s", idx, err)
 		}
@@ -671,9 +730,9 @@ func (ew *ElasticWriter) Finalize() error {
 	}
 
 	table := renderKVTable("ELK ingestion summary", rows)
-	// Print raw to stdout so the box-drawing characters are not mangled by
-	// a structured logger.
-	fmt.Print(table)
+	// Print raw (to stdout, or stderr when the results go to stdout) so the
+	// box-drawing characters are not mangled by a structured logger.
+	fmt.Fprint(logger.Output(), table)
 	return nil
 }
 
@@ -777,7 +836,7 @@ func renderKVTable(title string, rows [][2]string) string {
 	return b.String()
 }
 
-// hashable is satisfied by Credential, URL and Email (see models.go). Used
+// hashable is satisfied by Credential, URL, Email and Secret (see models.go). Used
 // by ingestItems to compute each doc's deterministic _id.
 type hashable interface {
 	CalcHash(string) string
@@ -830,17 +889,17 @@ func ingestItems[T hashable](ew *ElasticWriter, index string, items []T,
 }
 
 // writeSync performs the actual bulk HTTP calls against OpenSearch.
-// The three per-type ingestions (creds / urls / emails) run concurrently so
-// big files don't serialize them behind each other; the single-doc file
-// write is done after all three complete.
+// The four per-type ingestions (creds / urls / emails / secrets) run
+// concurrently so big files don't serialize them behind each other; the
+// single-doc file write is done after all four complete.
 func (ew *ElasticWriter) writeSync(result *models.File) error {
-	ew.logf("Integrating elastic (file=%s): %d credentials, %d e-mails, %d urls",
-		result.FileName, len(result.Credentials), len(result.Emails), len(result.URLs))
+	ew.logf("Integrating elastic (file=%s): %d credentials, %d e-mails, %d urls, %d secrets",
+		result.FileName, len(result.Credentials), len(result.Emails), len(result.URLs), len(result.Secrets))
 
 	var wg sync.WaitGroup
-	errs := make([]error, 3)
+	errs := make([]error, 4)
 
-	wg.Add(3)
+	wg.Add(4)
 	go func() {
 		defer wg.Done()
 		errs[0] = ingestItems(ew, ew.Index+"_creds",
@@ -856,6 +915,11 @@ func (ew *ElasticWriter) writeSync(result *models.File) error {
 		errs[2] = ingestItems(ew, ew.Index+"_emails",
 			result.Emails, result.Fingerprint, result.Bucket)
 	}()
+	go func() {
+		defer wg.Done()
+		errs[3] = ingestItems(ew, ew.Index+"_secrets",
+			result.Secrets, result.Fingerprint, result.Bucket)
+	}()
 	wg.Wait()
 
 	for _, e := range errs {
@@ -870,6 +934,7 @@ func (ew *ElasticWriter) writeSync(result *models.File) error {
 	fileDoc.Credentials = nil
 	fileDoc.Emails = nil
 	fileDoc.URLs = nil
+	fileDoc.Secrets = nil
 
 	b_data, err := json.Marshal(fileDoc)
 	if err != nil {
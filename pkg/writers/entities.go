@@ -0,0 +1,58 @@
+package writers
+
+import (
+	"github.com/helviojunior/intelparser/pkg/models"
+)
+
+// parent file columns, denormalized onto each entity row
+var entityParentHeaders = []string{"file_name", "file_path", "bucket", "provider", "leak_date", "fingerprint"}
+
+var entityHeaders = map[string][]string{
+	EntityCreds:   {"username", "password", "user_domain", "user_registrable_domain", "url", "url_domain", "url_registrable_domain", "cpf", "rule", "severity", "entropy", "client", "start_line", "end_line", "offset", "location"},
+	EntityEmails:  {"email", "domain", "registrable_domain", "client", "start_line", "end_line", "offset", "location"},
+	EntityUrls:    {"url", "domain", "registrable_domain", "client", "start_line", "end_line", "offset", "location"},
+	EntitySecrets: {"type", "value", "rule", "entropy", "client", "start_line", "end_line", "offset", "location"},
+}
+
+// entityColumns returns the headers of the entity rows
+func entityColumns(entity string) []string {
+	return append(append([]string{}, entityParentHeaders...), entityHeaders[entity]...)
+}
+
+// entityRows returns the rows of each entity found in the file, with the
+// file information on each row
+func entityRows(result *models.File) map[string][][]interface{} {
+	parent := []interface{}{
+		result.FileName,
+		result.FilePath,
+		result.Bucket,
+		result.Provider,
+		result.Date,
+		result.Fingerprint,
+	}
+
+	row := func(values ...interface{}) []interface{} {
+		return append(append([]interface{}{}, parent...), values...)
+	}
+
+	rows := map[string][][]interface{}{}
+	for _, c := range result.Credentials {
+		rows[EntityCreds] = append(rows[EntityCreds], row(
+			c.Username, c.Password, c.UserDomain, c.UserRegistrableDomain, c.Url, c.UrlDomain, c.UrlRegistrableDomain, c.CPF, c.Rule,
+			c.Severity, c.Entropy, c.Client, c.StartLine, c.EndLine, c.Offset, c.Location))
+	}
+	for _, e := range result.Emails {
+		rows[EntityEmails] = append(rows[EntityEmails], row(
+			e.Email, e.Domain, e.RegistrableDomain, e.Client, e.StartLine, e.EndLine, e.Offset, e.Location))
+	}
+	for _, u := range result.URLs {
+		rows[EntityUrls] = append(rows[EntityUrls], row(
+			u.Url, u.Domain, u.RegistrableDomain, u.Client, u.StartLine, u.EndLine, u.Offset, u.Location))
+	}
+	for _, s := range result.Secrets {
+		rows[EntitySecrets] = append(rows[EntitySecrets], row(
+			s.Type, s.Value, s.Rule, s.Entropy, s.Client, s.StartLine, s.EndLine, s.Offset, s.Location))
+	}
+
+	return rows
+}
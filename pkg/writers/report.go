@@ -0,0 +1,394 @@
+package writers
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/models"
+)
+
+// Report formats
+const (
+	ReportHtml     = "html"
+	ReportMarkdown = "markdown"
+)
+
+var ReportFormats = []string{ReportHtml, ReportMarkdown}
+
+//go:embed templates/report.html
+var reportHtmlTemplate string
+
+//go:embed templates/report.md
+var reportMarkdownTemplate string
+
+// credential severity levels, highest first
+var reportSeverities = []struct {
+	Name string
+	Min  int
+}{
+	{"Critical", 90},
+	{"High", 70},
+	{"Medium", 40},
+	{"Low", 0},
+}
+
+// ReportOptions are the report rendering options
+type ReportOptions struct {
+	Title         string
+	Format        string
+	MaskPasswords bool
+	// Number of users in the top affected users table
+	TopUsers int
+	// Maximum number of rows of each findings table
+	MaxRows int
+	// Filter terms, shown in the report header
+	Filter []string
+}
+
+// reportItem is a row of the summary tables
+type reportItem struct {
+	Name        string
+	Files       int
+	Credentials int
+	Sources     int
+	Percent     int
+}
+
+// reportTable is a findings table, limited to MaxRows rows
+type reportTable struct {
+	Title   string
+	Headers []string
+	Rows    [][]string
+	Total   int
+}
+
+func (t *reportTable) add(max int, row ...string) {
+	t.Total++
+	if len(t.Rows) < max {
+		t.Rows = append(t.Rows, row)
+	}
+}
+
+// reportUser is an affected user
+type reportUser struct {
+	credentials int
+	buckets     map[string]bool
+}
+
+// ReportWriter renders a self-contained (offline) HTML or Markdown leak
+// report. Write only aggregates the findings, the report is rendered on
+// Flush.
+type ReportWriter struct {
+	FilePath string
+	Options  ReportOptions
+
+	files     int
+	emails    int
+	urls      int
+	secrets   int
+	firstDate time.Time
+	lastDate  time.Time
+	severity  map[string]int
+	buckets   map[string]*reportItem
+	timeline  map[string]*reportItem
+	users     map[string]*reportUser
+	domains   map[string]bool
+	tables    []*reportTable
+
+	closed bool
+	mutex  sync.Mutex
+}
+
+// NewReportWriter returns a new report writer
+func NewReportWriter(destination string, options ReportOptions) (*ReportWriter, error) {
+	options.Format = strings.ToLower(options.Format)
+	if !tools.SliceHasStr(ReportFormats, options.Format) {
+		return nil, fmt.Errorf("invalid report format %q (use %s)", options.Format, strings.Join(ReportFormats, ", "))
+	}
+	if options.Title == "" {
+		options.Title = "Leak report"
+	}
+	if options.TopUsers <= 0 {
+		options.TopUsers = 20
+	}
+	if options.MaxRows <= 0 {
+		options.MaxRows = 500
+	}
+
+	if _, err := tools.CreateDir(filepath.Dir(destination)); err != nil {
+		return nil, err
+	}
+
+	return &ReportWriter{
+		FilePath: destination,
+		Options:  options,
+		severity: map[string]int{},
+		buckets:  map[string]*reportItem{},
+		timeline: map[string]*reportItem{},
+		users:    map[string]*reportUser{},
+		domains:  map[string]bool{},
+		tables: []*reportTable{
+			{Title: "Credentials", Headers: []string{"Username", "Password", "URL", "Bucket", "Leak date"}},
+			{Title: "E-mails", Headers: []string{"E-mail", "Bucket", "Leak date"}},
+			{Title: "URLs", Headers: []string{"URL", "Bucket", "Leak date"}},
+			{Title: "Secrets", Headers: []string{"Type", "Value", "Bucket", "Leak date"}},
+		},
+	}, nil
+}
+
+// Write aggregates the file findings
+func (rw *ReportWriter) Write(result *models.File) error {
+	rw.mutex.Lock()
+	defer rw.mutex.Unlock()
+
+	if rw.closed {
+		return fmt.Errorf("report %s is already rendered", rw.FilePath)
+	}
+
+	rw.files++
+	rw.emails += len(result.Emails)
+	rw.urls += len(result.URLs)
+	rw.secrets += len(result.Secrets)
+
+	if !result.Date.IsZero() {
+		if rw.firstDate.IsZero() || result.Date.Before(rw.firstDate) {
+			rw.firstDate = result.Date
+		}
+		if result.Date.After(rw.lastDate) {
+			rw.lastDate = result.Date
+		}
+	}
+
+	bucket := rw.item(rw.buckets, result.Bucket)
+	bucket.Files++
+	bucket.Credentials += len(result.Credentials)
+
+	month := "Unknown"
+	if !result.Date.IsZero() {
+		month = result.Date.Format("2006-01")
+	}
+	period := rw.item(rw.timeline, month)
+	period.Files++
+	period.Credentials += len(result.Credentials)
+
+	date := ""
+	if !result.Date.IsZero() {
+		date = result.Date.Format("2006-01-02")
+	}
+	max := rw.Options.MaxRows
+
+	for _, c := range result.Credentials {
+		rw.severity[severityName(c.Severity)]++
+
+		if c.UserDomain != "" {
+			rw.domains[strings.ToLower(c.UserDomain)] = true
+		}
+
+		if user := strings.ToLower(strings.TrimSpace(c.Username)); user != "" {
+			u, ok := rw.users[user]
+			if !ok {
+				u = &reportUser{buckets: map[string]bool{}}
+				rw.users[user] = u
+			}
+			u.credentials++
+			u.buckets[result.Bucket] = true
+		}
+
+		rw.tables[0].add(max, c.Username, rw.mask(c.Password), c.Url, result.Bucket, date)
+	}
+	for _, e := range result.Emails {
+		rw.tables[1].add(max, e.Email, result.Bucket, date)
+	}
+	for _, u := range result.URLs {
+		rw.tables[2].add(max, u.Url, result.Bucket, date)
+	}
+	for _, s := range result.Secrets {
+		rw.tables[3].add(max, s.Type, rw.mask(s.Value), result.Bucket, date)
+	}
+
+	return nil
+}
+
+func (rw *ReportWriter) item(items map[string]*reportItem, name string) *reportItem {
+	if name == "" {
+		name = "Unknown"
+	}
+	if _, ok := items[name]; !ok {
+		items[name] = &reportItem{Name: name}
+	}
+	return items[name]
+}
+
+func (rw *ReportWriter) mask(value string) string {
+	if !rw.Options.MaskPasswords {
+		return value
+	}
+	return maskValue(value)
+}
+
+// maskValue keeps only the first and the last chars of the value
+func maskValue(value string) string {
+	r := []rune(value)
+	if len(r) <= 2 {
+		return strings.Repeat("*", len(r))
+	}
+	return string(r[0]) + strings.Repeat("*", len(r)-2) + string(r[len(r)-1])
+}
+
+func severityName(severity int) string {
+	for _, s := range reportSeverities {
+		if severity >= s.Min {
+			return s.Name
+		}
+	}
+	return reportSeverities[len(reportSeverities)-1].Name
+}
+
+// Flush renders the report. Writes after Flush fail.
+func (rw *ReportWriter) Flush() error {
+	rw.mutex.Lock()
+	defer rw.mutex.Unlock()
+
+	if rw.closed {
+		return nil
+	}
+	rw.closed = true
+
+	var buf bytes.Buffer
+	var err error
+	if rw.Options.Format == ReportMarkdown {
+		err = template.Must(template.New("report").Funcs(reportFuncs()).Parse(reportMarkdownTemplate)).Execute(&buf, rw.data())
+	} else {
+		err = htmltemplate.Must(htmltemplate.New("report").Funcs(reportFuncs()).Parse(reportHtmlTemplate)).Execute(&buf, rw.data())
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(rw.FilePath, buf.Bytes(), 0644)
+}
+
+// data returns the report template data
+func (rw *ReportWriter) data() map[string]interface{} {
+	credentials := rw.tables[0].Total
+
+	severity := []reportItem{}
+	for _, s := range reportSeverities {
+		if cnt := rw.severity[s.Name]; cnt > 0 {
+			severity = append(severity, reportItem{Name: s.Name, Credentials: cnt, Percent: percent(cnt, credentials)})
+		}
+	}
+
+	buckets := sortedItems(rw.buckets, func(a, b *reportItem) bool {
+		if a.Credentials != b.Credentials {
+			return a.Credentials > b.Credentials
+		}
+		return a.Files > b.Files
+	})
+	for i := range buckets {
+		buckets[i].Percent = percent(buckets[i].Credentials, credentials)
+	}
+
+	timeline := sortedItems(rw.timeline, func(a, b *reportItem) bool {
+		return a.Name < b.Name
+	})
+	peak := 0
+	for _, t := range timeline {
+		if t.Files > peak {
+			peak = t.Files
+		}
+	}
+	for i := range timeline {
+		timeline[i].Percent = percent(timeline[i].Files, peak)
+	}
+
+	users := make([]reportItem, 0, len(rw.users))
+	for name, u := range rw.users {
+		users = append(users, reportItem{Name: name, Credentials: u.credentials, Sources: len(u.buckets)})
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Credentials != users[j].Credentials {
+			return users[i].Credentials > users[j].Credentials
+		}
+		return users[i].Name < users[j].Name
+	})
+	if len(users) > rw.Options.TopUsers {
+		users = users[:rw.Options.TopUsers]
+	}
+
+	first, last := "", ""
+	if !rw.firstDate.IsZero() {
+		first = rw.firstDate.Format("2006-01-02")
+		last = rw.lastDate.Format("2006-01-02")
+	}
+
+	return map[string]interface{}{
+		"Title":       rw.Options.Title,
+		"Generated":   time.Now().Format("2006-01-02 15:04:05"),
+		"Filter":      strings.Join(rw.Options.Filter, ", "),
+		"Masked":      rw.Options.MaskPasswords,
+		"Files":       rw.files,
+		"Credentials": credentials,
+		"Emails":      rw.emails,
+		"URLs":        rw.urls,
+		"Secrets":     rw.secrets,
+		"Users":       len(rw.users),
+		"Domains":     len(rw.domains),
+		"Sources":     len(rw.buckets),
+		"FirstDate":   first,
+		"LastDate":    last,
+		"Severity":    severity,
+		"Buckets":     buckets,
+		"Timeline":    timeline,
+		"TopUsers":    users,
+		"Tables":      rw.tables,
+	}
+}
+
+func sortedItems(items map[string]*reportItem, less func(a, b *reportItem) bool) []reportItem {
+	list := make([]reportItem, 0, len(items))
+	for _, i := range items {
+		list = append(list, *i)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if less(&list[i], &list[j]) {
+			return true
+		}
+		if less(&list[j], &list[i]) {
+			return false
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func percent(value int, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return value * 100 / total
+}
+
+func reportFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"comma": tools.FormatIntComma,
+		// escapes a Markdown table cell
+		"cell": func(s string) string {
+			s = strings.Join(strings.Fields(s), " ")
+			return strings.ReplaceAll(s, "|", "\\|")
+		},
+		"bar": func(p int) string {
+			return strings.Repeat("█", (p+4)/5)
+		},
+	}
+}
@@ -1,24 +1,217 @@
 package writers
 
 import (
-	//"fmt"
-	//"os"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/helviojunior/intelparser/internal/tools"
 	"github.com/helviojunior/intelparser/pkg/models"
-	logger "github.com/helviojunior/intelparser/pkg/log"
 )
 
-// StdoutWriter is a Stdout writer
+// Stdout formats
+const (
+	StdoutJsonl = "jsonl"
+	StdoutTsv   = "tsv"
+	StdoutTable = "table"
+)
+
+// Stdout entities
+const (
+	EntityCreds   = "creds"
+	EntityEmails  = "emails"
+	EntityUrls    = "urls"
+	EntitySecrets = "secrets"
+)
+
+var StdoutFormats = []string{StdoutJsonl, StdoutTsv, StdoutTable}
+var StdoutEntities = []string{EntityCreds, EntityEmails, EntityUrls, EntitySecrets}
+
+var (
+	tableHeaderStyle = lipgloss.NewStyle().Bold(true)
+	tableCredStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("204"))
+	tableEmailStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("86"))
+	tableUrlStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+	tableSecretStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	tableFileStyle   = lipgloss.NewStyle().Faint(true)
+)
+
+// StdoutWriter is a Stdout writer. It emits one line per finding (JSON,
+// TSV or a colored table row), so the results can be used in a shell
+// pipeline.
 type StdoutWriter struct {
+	Format   string
+	Entities []string
+
+	out    io.Writer
+	mutex  sync.Mutex
+	header bool
 }
 
 // NewStdoutWriter initialises a stdout writer
-func NewStdoutWriter() (*StdoutWriter, error) {
-	return &StdoutWriter{}, nil
+func NewStdoutWriter(format string, entities []string) (*StdoutWriter, error) {
+	format = strings.ToLower(format)
+	if format == "" {
+		format = StdoutJsonl
+	}
+	if !tools.SliceHasStr(StdoutFormats, format) {
+		return nil, fmt.Errorf("invalid stdout format %q (use %s)", format, strings.Join(StdoutFormats, ", "))
+	}
+
+	selected := []string{}
+	for _, e := range entities {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" {
+			continue
+		}
+		if !tools.SliceHasStr(StdoutEntities, e) {
+			return nil, fmt.Errorf("invalid stdout entity %q (use %s)", e, strings.Join(StdoutEntities, ", "))
+		}
+		selected = append(selected, e)
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("at least one stdout entity must be selected")
+	}
+
+	return &StdoutWriter{
+		Format:   format,
+		Entities: selected,
+		out:      os.Stdout,
+	}, nil
 }
 
 // Write results to stdout
 func (s *StdoutWriter) Write(result *models.File) error {
-	logger.Debugf("Finishing %s", result.FileName)
-	return nil
+	lines := []string{}
+
+	if s.wants(EntityCreds) {
+		for _, c := range result.Credentials {
+			lines = append(lines, s.credLine(result, c))
+		}
+	}
+	if s.wants(EntityEmails) {
+		for _, e := range result.Emails {
+			lines = append(lines, s.emailLine(result, e))
+		}
+	}
+	if s.wants(EntityUrls) {
+		for _, u := range result.URLs {
+			lines = append(lines, s.urlLine(result, u))
+		}
+	}
+	if s.wants(EntitySecrets) {
+		for _, sc := range result.Secrets {
+			lines = append(lines, s.secretLine(result, sc))
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.Format == StdoutTable && !s.header {
+		s.header = true
+		fmt.Fprintln(s.out, tableHeaderStyle.Render(tableRow("TYPE", "VALUE", "DETAIL")+"FILE"))
+	}
+
+	_, err := io.WriteString(s.out, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func (s *StdoutWriter) wants(entity string) bool {
+	return tools.SliceHasStr(s.Entities, entity)
+}
+
+func (s *StdoutWriter) credLine(file *models.File, c models.Credential) string {
+	switch s.Format {
+	case StdoutTsv:
+		return tsvRow(c.Username, c.Password, c.Url)
+	case StdoutTable:
+		return tableCredStyle.Render(tableRow("credential", c.Username+":"+c.Password, c.Url)) +
+			tableFileStyle.Render(file.FilePath)
+	}
+	return jsonLine("credential", file, c)
+}
+
+func (s *StdoutWriter) emailLine(file *models.File, e models.Email) string {
+	switch s.Format {
+	case StdoutTsv:
+		return tsvRow(e.Email, e.Domain)
+	case StdoutTable:
+		return tableEmailStyle.Render(tableRow("email", e.Email, e.Domain)) +
+			tableFileStyle.Render(file.FilePath)
+	}
+	return jsonLine("email", file, e)
+}
+
+func (s *StdoutWriter) urlLine(file *models.File, u models.URL) string {
+	switch s.Format {
+	case StdoutTsv:
+		return tsvRow(u.Url, u.Domain)
+	case StdoutTable:
+		return tableUrlStyle.Render(tableRow("url", u.Url, u.Domain)) +
+			tableFileStyle.Render(file.FilePath)
+	}
+	return jsonLine("url", file, u)
+}
+
+func (s *StdoutWriter) secretLine(file *models.File, sc models.Secret) string {
+	switch s.Format {
+	case StdoutTsv:
+		return tsvRow(sc.Type, sc.Value)
+	case StdoutTable:
+		return tableSecretStyle.Render(tableRow("secret", sc.Value, sc.Type)) +
+			tableFileStyle.Render(file.FilePath)
+	}
+	return jsonLine("secret", file, sc)
+}
+
+// jsonLine returns the entity JSON with the entity type and the file
+// information added
+func jsonLine(entity string, file *models.File, v interface{}) string {
+	data := map[string]interface{}{}
+	if b, err := json.Marshal(v); err == nil {
+		_ = json.Unmarshal(b, &data)
+	}
+
+	data["entity"] = entity
+	data["file_path"] = file.FilePath
+	data["provider"] = file.Provider
+	data["bucket"] = file.Bucket
+	data["leak_date"] = file.Date.Format(time.RFC3339)
+	data["fingerprint"] = file.Fingerprint
+
+	b, _ := json.Marshal(data)
+	return string(b)
+}
+
+// tsvRow joins the fields with TAB, replacing the TAB and line breaks
+// inside them
+func tsvRow(fields ...string) string {
+	r := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+	for i, f := range fields {
+		fields[i] = r.Replace(f)
+	}
+	return strings.Join(fields, "\t")
+}
+
+func tableRow(kind string, value string, detail string) string {
+	return fmt.Sprintf("%-11s %-50s %-30s ", kind, truncate(value, 50), truncate(detail, 30))
+}
+
+func truncate(s string, size int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len([]rune(s)) <= size {
+		return s
+	}
+	return string([]rune(s)[:size-3]) + "..."
 }
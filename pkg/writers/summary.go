@@ -0,0 +1,309 @@
+package writers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/helviojunior/intelparser/internal/tools"
+	"github.com/helviojunior/intelparser/pkg/models"
+)
+
+// Summary formats
+const (
+	SummaryTable = "table"
+	SummaryJson  = "json"
+)
+
+var SummaryFormats = []string{SummaryTable, SummaryJson}
+
+// SummaryCount is a name with its count
+type SummaryCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// SummaryGroup is a group of files (bucket or month) with its counts
+type SummaryGroup struct {
+	Name        string `json:"name"`
+	Files       int    `json:"files"`
+	Credentials int    `json:"credentials"`
+}
+
+// SummaryTotals are the overall counts
+type SummaryTotals struct {
+	Files       int `json:"files"`
+	Credentials int `json:"credentials"`
+	Emails      int `json:"emails"`
+	URLs        int `json:"urls"`
+	Secrets     int `json:"secrets"`
+	Users       int `json:"distinct_users"`
+	UserDomains int `json:"user_domains"`
+	UrlDomains  int `json:"url_domains"`
+}
+
+// Summary is the aggregate statistics of the results
+type Summary struct {
+	Totals          SummaryTotals  `json:"totals"`
+	UserDomains     []SummaryCount `json:"user_domains"`
+	UrlDomains      []SummaryCount `json:"url_domains"`
+	Buckets         []SummaryGroup `json:"buckets"`
+	Months          []SummaryGroup `json:"months"`
+	UsersInFiles    []SummaryCount `json:"users_in_multiple_files"`
+	SharedPasswords []SummaryCount `json:"shared_passwords"`
+}
+
+// SummaryWriter aggregates the results and prints their statistics (as
+// tables or JSON) on Flush.
+type SummaryWriter struct {
+	Format        string
+	Top           int
+	MaskPasswords bool
+
+	totals      SummaryTotals
+	userDomains map[string]int
+	urlDomains  map[string]int
+	buckets     map[string]*SummaryGroup
+	months      map[string]*SummaryGroup
+	userFiles   map[string]map[string]bool
+	passwords   map[string]map[string]bool
+
+	out    io.Writer
+	closed bool
+	mutex  sync.Mutex
+}
+
+// NewSummaryWriter returns a new summary writer
+func NewSummaryWriter(format string, top int, mask bool) (*SummaryWriter, error) {
+	format = strings.ToLower(format)
+	if !tools.SliceHasStr(SummaryFormats, format) {
+		return nil, fmt.Errorf("invalid summary format %q (use %s)", format, strings.Join(SummaryFormats, ", "))
+	}
+	if top <= 0 {
+		top = 10
+	}
+
+	return &SummaryWriter{
+		Format:        format,
+		Top:           top,
+		MaskPasswords: mask,
+		userDomains:   map[string]int{},
+		urlDomains:    map[string]int{},
+		buckets:       map[string]*SummaryGroup{},
+		months:        map[string]*SummaryGroup{},
+		userFiles:     map[string]map[string]bool{},
+		passwords:     map[string]map[string]bool{},
+		out:           os.Stdout,
+	}, nil
+}
+
+// Write aggregates the file results
+func (sw *SummaryWriter) Write(result *models.File) error {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	sw.totals.Files++
+	sw.totals.Credentials += len(result.Credentials)
+	sw.totals.Emails += len(result.Emails)
+	sw.totals.URLs += len(result.URLs)
+	sw.totals.Secrets += len(result.Secrets)
+
+	bucket := sw.group(sw.buckets, result.Bucket)
+	bucket.Files++
+	bucket.Credentials += len(result.Credentials)
+
+	month := "Unknown"
+	if !result.Date.IsZero() {
+		month = result.Date.Format("2006-01")
+	}
+	period := sw.group(sw.months, month)
+	period.Files++
+	period.Credentials += len(result.Credentials)
+
+	file := result.Fingerprint
+	if file == "" {
+		file = result.FilePath
+	}
+
+	for _, c := range result.Credentials {
+		if d := strings.ToLower(c.UserDomain); d != "" {
+			sw.userDomains[d]++
+		}
+		if d := strings.ToLower(c.UrlDomain); d != "" {
+			sw.urlDomains[d]++
+		}
+
+		user := strings.ToLower(strings.TrimSpace(c.Username))
+		if user == "" {
+			continue
+		}
+		if _, ok := sw.userFiles[user]; !ok {
+			sw.userFiles[user] = map[string]bool{}
+		}
+		sw.userFiles[user][file] = true
+
+		if c.Password == "" {
+			continue
+		}
+		if _, ok := sw.passwords[c.Password]; !ok {
+			sw.passwords[c.Password] = map[string]bool{}
+		}
+		sw.passwords[c.Password][user] = true
+	}
+
+	return nil
+}
+
+func (sw *SummaryWriter) group(groups map[string]*SummaryGroup, name string) *SummaryGroup {
+	if name == "" {
+		name = "Unknown"
+	}
+	if _, ok := groups[name]; !ok {
+		groups[name] = &SummaryGroup{Name: name}
+	}
+	return groups[name]
+}
+
+// Summary returns the aggregate statistics
+func (sw *SummaryWriter) Summary() Summary {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	s := Summary{
+		Totals:          sw.totals,
+		UserDomains:     topCounts(sw.userDomains, sw.Top, 1),
+		UrlDomains:      topCounts(sw.urlDomains, sw.Top, 1),
+		Buckets:         sortedGroups(sw.buckets, false),
+		Months:          sortedGroups(sw.months, true),
+		UsersInFiles:    topCounts(setCounts(sw.userFiles), sw.Top, 2),
+		SharedPasswords: topCounts(setCounts(sw.passwords), sw.Top, 2),
+	}
+	s.Totals.Users = len(sw.userFiles)
+	s.Totals.UserDomains = len(sw.userDomains)
+	s.Totals.UrlDomains = len(sw.urlDomains)
+
+	if len(s.Buckets) > sw.Top {
+		s.Buckets = s.Buckets[:sw.Top]
+	}
+	if sw.MaskPasswords {
+		for i := range s.SharedPasswords {
+			s.SharedPasswords[i].Name = maskValue(s.SharedPasswords[i].Name)
+		}
+	}
+
+	return s
+}
+
+// Flush prints the summary. Further calls are no-op.
+func (sw *SummaryWriter) Flush() error {
+	sw.mutex.Lock()
+	if sw.closed {
+		sw.mutex.Unlock()
+		return nil
+	}
+	sw.closed = true
+	sw.mutex.Unlock()
+
+	s := sw.Summary()
+
+	if sw.Format == SummaryJson {
+		enc := json.NewEncoder(sw.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+
+	t := s.Totals
+	tables := []string{
+		renderKVTable("Totals", [][2]string{
+			{"Files", fmtInt(int64(t.Files))},
+			{"Credentials", fmtInt(int64(t.Credentials))},
+			{"E-mails", fmtInt(int64(t.Emails))},
+			{"URLs", fmtInt(int64(t.URLs))},
+			{"Secrets", fmtInt(int64(t.Secrets))},
+			{"Distinct users", fmtInt(int64(t.Users))},
+			{"User domains", fmtInt(int64(t.UserDomains))},
+			{"URL domains", fmtInt(int64(t.UrlDomains))},
+		}),
+		renderKVTable("Credentials per user domain", countRows(s.UserDomains, "credentials")),
+		renderKVTable("Credentials per URL domain", countRows(s.UrlDomains, "credentials")),
+		renderKVTable("Top leaking buckets", groupRows(s.Buckets)),
+		renderKVTable("Leaks per month", groupRows(s.Months)),
+		renderKVTable("Users in multiple files", countRows(s.UsersInFiles, "files")),
+		renderKVTable("Passwords shared by several accounts", countRows(s.SharedPasswords, "accounts")),
+	}
+
+	_, err := fmt.Fprintln(sw.out, strings.Join(tables, "\n"))
+	return err
+}
+
+// setCounts returns the size of each set
+func setCounts(sets map[string]map[string]bool) map[string]int {
+	counts := make(map[string]int, len(sets))
+	for k, v := range sets {
+		counts[k] = len(v)
+	}
+	return counts
+}
+
+// topCounts returns the top counts (highest first) with at least min
+func topCounts(counts map[string]int, top int, min int) []SummaryCount {
+	list := []SummaryCount{}
+	for name, cnt := range counts {
+		if cnt >= min {
+			list = append(list, SummaryCount{Name: name, Count: cnt})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+	if len(list) > top {
+		list = list[:top]
+	}
+	return list
+}
+
+// sortedGroups returns the groups by name or by credentials (highest first)
+func sortedGroups(groups map[string]*SummaryGroup, byName bool) []SummaryGroup {
+	list := make([]SummaryGroup, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, *g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !byName {
+			if list[i].Credentials != list[j].Credentials {
+				return list[i].Credentials > list[j].Credentials
+			}
+			if list[i].Files != list[j].Files {
+				return list[i].Files > list[j].Files
+			}
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func countRows(counts []SummaryCount, unit string) [][2]string {
+	if len(counts) == 0 {
+		return [][2]string{{"(none)", "-"}}
+	}
+	rows := make([][2]string, 0, len(counts))
+	for _, c := range counts {
+		rows = append(rows, [2]string{c.Name, fmtInt(int64(c.Count)) + " " + unit})
+	}
+	return rows
+}
+
+func groupRows(groups []SummaryGroup) [][2]string {
+	rows := make([][2]string, 0, len(groups))
+	for _, g := range groups {
+		rows = append(rows, [2]string{g.Name, fmt.Sprintf("%s files, %s credentials", fmtInt(int64(g.Files)), fmtInt(int64(g.Credentials)))})
+	}
+	return rows
+}
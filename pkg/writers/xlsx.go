@@ -0,0 +1,320 @@
+package writers
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/helviojunior/intelparser/internal/tools"
+	logger "github.com/helviojunior/intelparser/pkg/log"
+	"github.com/helviojunior/intelparser/pkg/models"
+	"github.com/xuri/excelize/v2"
+)
+
+// Xlsx sheets
+const (
+	xlsxSummary = "Summary"
+	xlsxFiles   = "Files"
+)
+
+var xlsxEntitySheets = map[string]string{
+	EntityCreds:   "Credentials",
+	EntityEmails:  "Emails",
+	EntityUrls:    "URLs",
+	EntitySecrets: "Secrets",
+}
+
+var xlsxFileHeaders = []string{
+	"file_name", "file_path", "name", "bucket", "provider", "provider_id", "leak_date",
+	"mail_from", "mail_subject",
+	"indexed_at", "size", "mime_type", "fingerprint", "credentials", "emails", "urls",
+	"secrets", "failed", "failed_reason",
+}
+
+var xlsxSummaryHeaders = []string{"domain", "credentials", "emails", "urls", "total"}
+
+// xlsxSheet is a sheet being written in stream mode
+type xlsxSheet struct {
+	name    string
+	columns int
+	rows    int
+	dropped int
+	stream  *excelize.StreamWriter
+}
+
+// XlsxWriter is an Excel (XLSX) writer, with one sheet per entity and a
+// summary sheet with the counts per domain. The rows are streamed to
+// temporary files, so the workbook is only built on Flush.
+type XlsxWriter struct {
+	FilePath string
+
+	file    *excelize.File
+	sheets  map[string]*xlsxSheet
+	domains map[string]*[3]int
+	header  int
+	date    int
+	closed  bool
+	mutex   sync.Mutex
+}
+
+// NewXlsxWriter returns a new XLSX writer
+func NewXlsxWriter(destination string) (*XlsxWriter, error) {
+	if _, err := tools.CreateDir(filepath.Dir(destination)); err != nil {
+		return nil, err
+	}
+
+	f := excelize.NewFile()
+	if err := f.SetSheetName(f.GetSheetName(0), xlsxSummary); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	xw := &XlsxWriter{
+		FilePath: destination,
+		file:     f,
+		sheets:   map[string]*xlsxSheet{},
+		domains:  map[string]*[3]int{},
+	}
+
+	var err error
+	if xw.header, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if xw.date, err = f.NewStyle(&excelize.Style{NumFmt: 22}); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if err = xw.addSheet(xlsxFiles, xlsxFileHeaders); err != nil {
+		f.Close()
+		return nil, err
+	}
+	for _, entity := range StdoutEntities {
+		if err = xw.addSheet(xlsxEntitySheets[entity], entityColumns(entity)); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	return xw, nil
+}
+
+// addSheet creates the sheet with a frozen header row
+func (xw *XlsxWriter) addSheet(name string, headers []string) error {
+	if name != xlsxSummary {
+		if _, err := xw.file.NewSheet(name); err != nil {
+			return err
+		}
+	}
+
+	stream, err := xw.file.NewStreamWriter(name)
+	if err != nil {
+		return err
+	}
+
+	// Panes must be set before the first row
+	if err = stream.SetPanes(&excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return err
+	}
+
+	row := make([]interface{}, len(headers))
+	for i, h := range headers {
+		row[i] = excelize.Cell{StyleID: xw.header, Value: h}
+	}
+	if err = stream.SetRow("A1", row); err != nil {
+		return err
+	}
+
+	xw.sheets[name] = &xlsxSheet{
+		name:    name,
+		columns: len(headers),
+		rows:    1,
+		stream:  stream,
+	}
+	return nil
+}
+
+// Write the file and its entities
+func (xw *XlsxWriter) Write(result *models.File) error {
+	rows := entityRows(result)
+
+	xw.mutex.Lock()
+	defer xw.mutex.Unlock()
+
+	if xw.closed {
+		return fmt.Errorf("xlsx file %s is already closed", xw.FilePath)
+	}
+
+	if err := xw.writeRow(xw.sheets[xlsxFiles], []interface{}{
+		result.FileName,
+		result.FilePath,
+		result.Name,
+		result.Bucket,
+		result.Provider,
+		result.ProviderId,
+		result.Date,
+		result.MailFrom,
+		result.MailSubject,
+		result.IndexedAt,
+		result.Size,
+		result.MIMEType,
+		result.Fingerprint,
+		len(result.Credentials),
+		len(result.Emails),
+		len(result.URLs),
+		len(result.Secrets),
+		result.Failed,
+		result.FailedReason,
+	}); err != nil {
+		return err
+	}
+
+	for _, entity := range StdoutEntities {
+		for _, row := range rows[entity] {
+			if err := xw.writeRow(xw.sheets[xlsxEntitySheets[entity]], row); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, c := range result.Credentials {
+		domain := c.UserDomain
+		if domain == "" {
+			domain = c.UrlDomain
+		}
+		xw.countDomain(domain, 0)
+	}
+	for _, e := range result.Emails {
+		xw.countDomain(e.Domain, 1)
+	}
+	for _, u := range result.URLs {
+		xw.countDomain(u.Domain, 2)
+	}
+
+	return nil
+}
+
+// writeRow appends the row to the sheet. Rows beyond the Excel limit are
+// dropped (and reported on Flush).
+func (xw *XlsxWriter) writeRow(sheet *xlsxSheet, values []interface{}) error {
+	if sheet.rows >= excelize.TotalRows {
+		sheet.dropped++
+		return nil
+	}
+
+	row := make([]interface{}, len(values))
+	for i, v := range values {
+		if t, ok := v.(time.Time); ok {
+			if t.IsZero() {
+				row[i] = ""
+				continue
+			}
+			row[i] = excelize.Cell{StyleID: xw.date, Value: t}
+			continue
+		}
+		row[i] = v
+	}
+
+	cell, err := excelize.CoordinatesToCellName(1, sheet.rows+1)
+	if err != nil {
+		return err
+	}
+	if err = sheet.stream.SetRow(cell, row); err != nil {
+		return err
+	}
+	sheet.rows++
+	return nil
+}
+
+func (xw *XlsxWriter) countDomain(domain string, kind int) {
+	if domain == "" {
+		domain = "(none)"
+	}
+	if _, ok := xw.domains[domain]; !ok {
+		xw.domains[domain] = &[3]int{}
+	}
+	xw.domains[domain][kind]++
+}
+
+// Flush writes the summary sheet, adds the auto-filters and saves the
+// workbook. Writes after Flush fail.
+func (xw *XlsxWriter) Flush() error {
+	xw.mutex.Lock()
+	defer xw.mutex.Unlock()
+
+	if xw.closed {
+		return nil
+	}
+	xw.closed = true
+	defer xw.file.Close()
+
+	if err := xw.addSheet(xlsxSummary, xlsxSummaryHeaders); err != nil {
+		return err
+	}
+
+	domains := make([]string, 0, len(xw.domains))
+	for d := range xw.domains {
+		domains = append(domains, d)
+	}
+	total := func(d string) int {
+		c := xw.domains[d]
+		return c[0] + c[1] + c[2]
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		ti, tj := total(domains[i]), total(domains[j])
+		if ti != tj {
+			return ti > tj
+		}
+		return domains[i] < domains[j]
+	})
+
+	summary := xw.sheets[xlsxSummary]
+	for _, d := range domains {
+		c := xw.domains[d]
+		if err := xw.writeRow(summary, []interface{}{d, c[0], c[1], c[2], total(d)}); err != nil {
+			return err
+		}
+	}
+
+	names := []string{xlsxSummary, xlsxFiles}
+	for _, entity := range StdoutEntities {
+		names = append(names, xlsxEntitySheets[entity])
+	}
+
+	for _, name := range names {
+		sheet := xw.sheets[name]
+		if sheet.dropped > 0 {
+			logger.Warnf("XLSX sheet %s reached the Excel row limit, %d rows were not written", name, sheet.dropped)
+		}
+
+		// The table gives the auto-filter on the header row
+		if sheet.rows > 1 {
+			last, err := excelize.CoordinatesToCellName(sheet.columns, sheet.rows)
+			if err != nil {
+				return err
+			}
+			if err = sheet.stream.AddTable(&excelize.Table{
+				Range:     "A1:" + last,
+				Name:      "tbl" + name,
+				StyleName: "TableStyleLight9",
+			}); err != nil {
+				return err
+			}
+		}
+
+		if err := sheet.stream.Flush(); err != nil {
+			return err
+		}
+	}
+
+	xw.file.SetActiveSheet(0)
+	return xw.file.SaveAs(xw.FilePath)
+}